@@ -1,58 +1,160 @@
 package main
 
 import (
-	"os"
+	"fmt"
+	"log"
 
-	"bg_deploy_sample/components/deployment"
-	"bg_deploy_sample/components/network"
-	"bg_deploy_sample/components/service"
+	"bg_deploy_sample/config"
+
+	"iaclib/deployment"
+	"iaclib/network"
+	"iaclib/security"
+	"iaclib/service"
 
 	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awssecretsmanager"
 	"github.com/aws/constructs-go/constructs/v10"
 	"github.com/aws/jsii-runtime-go"
-	"github.com/joho/godotenv"
 )
 
+// ブルーグリーンデプロイで新しいタスクを確認するテストリスナーのポート
+const testListenerPort = 8080
+
 type BgDeploySampleStackProps struct {
 	awscdk.StackProps
+	Config *config.Config
 }
 
 func NewBgDeploySampleStack(scope constructs.Construct, id string, props *BgDeploySampleStackProps) awscdk.Stack {
-	var sprops awscdk.StackProps
-	if props != nil {
-		sprops = props.StackProps
+	// 設定は config.Load で読み込んで検証したものを必ず渡す
+	if props == nil || props.Config == nil {
+		panic("NewBgDeploySampleStack: props.Config is required (load it with config.Load)")
 	}
+	sprops := props.StackProps
 	stack := awscdk.NewStack(scope, &id, &sprops)
+	cfg := props.Config
+
+	// 管理者相当の管理ポリシーをアタッチしたロールがあれば synth を失敗させる
+	awscdk.Aspects_Of(stack).Add(security.NewManagedPolicyGuard(), nil)
 
-	network := network.NewNetwork(stack)
+	// 本番リスナー(80)とテストリスナー(8080)
+	network := network.NewAppNetwork(stack, "Network", &network.AppNetworkProps{
+		ResourceName:       cfg.ResourceName,
+		InterfaceEndpoints: cfg.InterfaceEndpoints,
+		ContainerPort:      80,
+		HealthCheckPath:    "/",
+		TestListener: &network.TestListenerProps{
+			Port: testListenerPort,
+		},
+	})
+	security.Suppress(network.AlbSecurityGroup, security.RulePublicIngress, "ライフサイクルフックの Lambda は VPC の外からテストリスナーにアクセスするため、8080 も公開する")
 
-	service := service.NewService(stack, network)
+	service := service.NewFargateWebService(stack, "Service", &service.FargateWebServiceProps{
+		ResourceName:   cfg.ResourceName,
+		Vpc:            network.Vpc,
+		SecurityGroup:  network.EcsSecurityGroup,
+		TargetGroup:    network.TargetGroup1,
+		RepositoryName: cfg.RepositoryName,
+		ContainerName:  "nginx",
+		ContainerPort:  80,
+		Cpu:            256,
+		MemoryLimitMiB: 512,
+		DesiredCount:   1,
+		Environment: map[string]*string{
+			"TZ": jsii.String("Asia/Tokyo"),
+		},
+		BlueGreen:              true,
+		HealthCheckGracePeriod: awscdk.Duration_Hours(jsii.Number(1)),
+		// 負荷に応じて 1〜4 タスクの範囲で調整する
+		AutoScaling: &service.AutoScalingProps{
+			MinCapacity:       1,
+			MaxCapacity:       4,
+			CpuUtilization:    60,
+			MemoryUtilization: 75,
+			RequestsPerTarget: 1000,
+			ScaleInCooldown:   awscdk.Duration_Seconds(jsii.Number(300)),
+			ScaleOutCooldown:  awscdk.Duration_Seconds(jsii.Number(60)),
+		},
+	})
 
-	deployment.NewDeployment(stack, network, service)
+	deployment.NewBlueGreenPipeline(stack, "Pipeline", &deployment.BlueGreenPipelineProps{
+		BlueGreenDeploymentProps: deployment.BlueGreenDeploymentProps{
+			ResourceName:     cfg.ResourceName,
+			Service:          service.Service,
+			TaskDefinition:   service.TaskDef,
+			BlueTargetGroup:  network.TargetGroup1,
+			GreenTargetGroup: network.TargetGroup2,
+			ProdListener:     network.ProdListener,
+			TestListener:     network.TestListener,
+			// 本番トラフィックを切り替える前後に、テストリスナーで新しいタスクを確認する
+			SmokeTests: &deployment.SmokeTestProps{
+				TestEndpoint:       fmt.Sprintf("http://%s:%d", *network.Alb.LoadBalancerDnsName(), testListenerPort),
+				BeforeAllowTraffic: cfg.BeforeAllowTrafficChecks,
+				AfterAllowTraffic:  cfg.AfterAllowTrafficChecks,
+			},
+		},
+		GitHubConnectionArn:   cfg.GitHubConnectionArn,
+		GitHubRepositoryOwner: cfg.GitHubRepositoryOwner,
+		GitHubRepositoryName:  cfg.GitHubRepositoryName,
+		GitHubBranchName:      cfg.GitHubBranchName,
+		Repository:            service.Repository,
+		PullRequestValidation: pullRequestValidation(cfg),
+		Notifications:         notifications(stack, cfg),
+	})
 
 	return stack
 }
 
-func main() {
-	defer jsii.Close()
+// 設定で有効にした場合のみ、プルリクエストの検証用プロジェクトを作成する
+func pullRequestValidation(cfg *config.Config) *deployment.PullRequestValidationProps {
+	if !cfg.PullRequestValidation {
+		return nil
+	}
+	return &deployment.PullRequestValidationProps{
+		TestCommands: cfg.PullRequestTestCommands,
+	}
+}
+
+// 通知先が設定されている場合のみ、パイプラインの通知を作成する
+func notifications(scope constructs.Construct, cfg *config.Config) *deployment.NotificationProps {
+	if len(cfg.NotificationEmails) == 0 && cfg.NotificationWebhookSecret == "" {
+		return nil
+	}
+	props := &deployment.NotificationProps{
+		Emails: cfg.NotificationEmails,
+	}
+	if cfg.NotificationWebhookSecret != "" {
+		props.WebhookSecret = awssecretsmanager.Secret_FromSecretNameV2(scope, jsii.String("NotificationWebhookSecret"), jsii.String(cfg.NotificationWebhookSecret))
+	}
+	return props
+}
 
+func main() {
 	// 環境変数読み込み
-	godotenv.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	defer jsii.Close()
 
 	app := awscdk.NewApp(nil)
+	// 暗号化、公開ポート、IAM のワイルドカードなどを検査し、違反があれば synth を失敗させる
+	awscdk.Aspects_Of(app).Add(security.NewComplianceChecks(), nil)
 
 	NewBgDeploySampleStack(app, "BgDeploySampleStack", &BgDeploySampleStackProps{
-		awscdk.StackProps{
-			Env: env(),
+		StackProps: awscdk.StackProps{
+			Env: env(cfg),
 		},
+		Config: cfg,
 	})
 
 	app.Synth(nil)
 }
 
-func env() *awscdk.Environment {
+func env(cfg *config.Config) *awscdk.Environment {
 	return &awscdk.Environment{
-		Account: jsii.String(os.Getenv("ACCOUNT_ID")),
-		Region:  jsii.String(os.Getenv("REGION")),
+		Account: jsii.String(cfg.AccountID),
+		Region:  jsii.String(cfg.Region),
 	}
 }
@@ -1,26 +1,465 @@
 package main
 
-// import (
-// 	"testing"
-
-// 	"github.com/aws/aws-cdk-go/awscdk/v2"
-// 	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
-// 	"github.com/aws/jsii-runtime-go"
-// )
-
-// example tests. To run these tests, uncomment this file along with the
-// example resource in bg_deploy_sample_test.go
-// func TestBgDeploySampleStack(t *testing.T) {
-// 	// GIVEN
-// 	app := awscdk.NewApp(nil)
-
-// 	// WHEN
-// 	stack := NewBgDeploySampleStack(app, "MyStack", nil)
-
-// 	// THEN
-// 	template := assertions.Template_FromStack(stack, nil)
-
-// 	template.HasResourceProperties(jsii.String("AWS::SQS::Queue"), map[string]interface{}{
-// 		"VisibilityTimeout": 300,
-// 	})
-// }
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"bg_deploy_sample/config"
+
+	"iaclib/deployment"
+	"iaclib/security"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	"github.com/aws/jsii-runtime-go"
+)
+
+const (
+	testAccount = "123456789012"
+	testRegion  = "ap-northeast-1"
+)
+
+func testConfig() *config.Config {
+	return &config.Config{
+		AccountID:             testAccount,
+		Region:                testRegion,
+		ResourceName:          "bg-deploy-test",
+		RepositoryName:        "bg-deploy-nginx",
+		GitHubConnectionArn:   "arn:aws:codestar-connections:ap-northeast-1:123456789012:connection/00000000-0000-0000-0000-000000000000",
+		GitHubRepositoryOwner: "example",
+		GitHubRepositoryName:  "bg-deploy-sample",
+		GitHubBranchName:      "main",
+		InterfaceEndpoints:    []string{"ecr.api", "ecr.dkr"},
+		BeforeAllowTrafficChecks: []deployment.SmokeCheck{
+			{Path: "/", ExpectedStatus: 200, BodyContains: "nginx"},
+		},
+		AfterAllowTrafficChecks: []deployment.SmokeCheck{
+			{Path: "/"},
+		},
+	}
+}
+
+// ネットワークへの問い合わせが発生しないよう、ルックアップ結果をコンテキストで固定する
+func testContext() *map[string]interface{} {
+	return &map[string]interface{}{
+		"availability-zones:account=" + testAccount + ":region=" + testRegion: []string{
+			"ap-northeast-1a",
+			"ap-northeast-1c",
+			"ap-northeast-1d",
+		},
+	}
+}
+
+func synthBgDeploySampleStack(t *testing.T, cfg *config.Config) assertions.Template {
+	t.Helper()
+
+	app := awscdk.NewApp(&awscdk.AppProps{
+		Context: testContext(),
+	})
+
+	stack := NewBgDeploySampleStack(app, "bg-deploy-test", &BgDeploySampleStackProps{
+		StackProps: awscdk.StackProps{
+			Env: env(cfg),
+		},
+		Config: cfg,
+	})
+
+	return assertions.Template_FromStack(stack, nil)
+}
+
+func TestBgDeploySampleStack(t *testing.T) {
+	// GIVEN
+	cfg := testConfig()
+
+	// WHEN
+	template := synthBgDeploySampleStack(t, cfg)
+
+	// THEN
+	t.Run("vpc has public and private subnets in two AZs", func(t *testing.T) {
+		template.ResourceCountIs(jsii.String("AWS::EC2::VPC"), jsii.Number(1))
+		template.ResourceCountIs(jsii.String("AWS::EC2::Subnet"), jsii.Number(4))
+		template.ResourceCountIs(jsii.String("AWS::EC2::NatGateway"), jsii.Number(0))
+	})
+
+	t.Run("security groups open production and test ports", func(t *testing.T) {
+		template.HasResourceProperties(jsii.String("AWS::EC2::SecurityGroup"), map[string]interface{}{
+			"GroupName": "bg-deploy-test-sg-alb",
+			"SecurityGroupIngress": assertions.Match_ArrayWith(&[]interface{}{
+				assertions.Match_ObjectLike(&map[string]interface{}{"CidrIp": "0.0.0.0/0", "FromPort": 80, "ToPort": 80}),
+				assertions.Match_ObjectLike(&map[string]interface{}{"CidrIp": "0.0.0.0/0", "FromPort": 8080, "ToPort": 8080}),
+			}),
+		})
+		// blue / green どちらのターゲットグループもコンテナの 80 番に転送する
+		template.ResourceCountIs(jsii.String("AWS::EC2::SecurityGroupIngress"), jsii.Number(1))
+		template.HasResourceProperties(jsii.String("AWS::EC2::SecurityGroupIngress"), map[string]interface{}{
+			"FromPort":              80,
+			"ToPort":                80,
+			"SourceSecurityGroupId": assertions.Match_AnyValue(),
+		})
+	})
+
+	t.Run("production and test listeners", func(t *testing.T) {
+		for _, port := range []int{80, 8080} {
+			template.HasResourceProperties(jsii.String("AWS::ElasticLoadBalancingV2::Listener"), map[string]interface{}{
+				"Port":     port,
+				"Protocol": "HTTP",
+			})
+		}
+	})
+
+	t.Run("blue and green target groups", func(t *testing.T) {
+		for _, name := range []string{"bg-deploy-test-tg1", "bg-deploy-test-tg2"} {
+			template.HasResourceProperties(jsii.String("AWS::ElasticLoadBalancingV2::TargetGroup"), map[string]interface{}{
+				"Name":                       name,
+				"Port":                       80,
+				"TargetType":                 "ip",
+				"HealthCheckPath":            "/",
+				"HealthCheckIntervalSeconds": 60,
+				"HealthCheckTimeoutSeconds":  30,
+			})
+		}
+	})
+
+	t.Run("fargate service is controlled by codedeploy", func(t *testing.T) {
+		template.HasResourceProperties(jsii.String("AWS::ECS::TaskDefinition"), map[string]interface{}{
+			"Cpu":    "256",
+			"Memory": "512",
+		})
+		template.HasResourceProperties(jsii.String("AWS::ECS::Service"), map[string]interface{}{
+			"ServiceName":  "bg-deploy-test-service",
+			"DesiredCount": 1,
+			"DeploymentController": map[string]interface{}{
+				"Type": "CODE_DEPLOY",
+			},
+		})
+	})
+
+	t.Run("service scales between one and four tasks", func(t *testing.T) {
+		template.HasResourceProperties(jsii.String("AWS::ApplicationAutoScaling::ScalableTarget"), map[string]interface{}{
+			"MinCapacity":       1,
+			"MaxCapacity":       4,
+			"ScalableDimension": "ecs:service:DesiredCount",
+		})
+		template.ResourceCountIs(jsii.String("AWS::ApplicationAutoScaling::ScalingPolicy"), jsii.Number(3))
+	})
+
+	t.Run("codedeploy blue/green deployment group", func(t *testing.T) {
+		template.HasResourceProperties(jsii.String("AWS::CodeDeploy::DeploymentGroup"), map[string]interface{}{
+			"DeploymentGroupName":  "bg-deploy-test-deployment-group",
+			"DeploymentConfigName": "CodeDeployDefault.ECSLinear10PercentEvery1Minutes",
+			"DeploymentStyle": map[string]interface{}{
+				"DeploymentOption": "WITH_TRAFFIC_CONTROL",
+				"DeploymentType":   "BLUE_GREEN",
+			},
+			"LoadBalancerInfo": map[string]interface{}{
+				"TargetGroupPairInfoList": []interface{}{
+					assertions.Match_ObjectLike(&map[string]interface{}{
+						"TargetGroups":     assertions.Match_AnyValue(),
+						"ProdTrafficRoute": assertions.Match_AnyValue(),
+						"TestTrafficRoute": assertions.Match_AnyValue(),
+					}),
+				},
+			},
+		})
+	})
+
+	t.Run("lifecycle hooks run smoke checks against the test listener", func(t *testing.T) {
+		template.HasResourceProperties(jsii.String("AWS::Lambda::Function"), map[string]interface{}{
+			"FunctionName": "bg-deploy-test-before-allow-traffic",
+			"Runtime":      "nodejs20.x",
+			"Environment": map[string]interface{}{
+				"Variables": map[string]interface{}{
+					"BASE_URL": map[string]interface{}{
+						"Fn::Join": []interface{}{"", []interface{}{"http://", assertions.Match_AnyValue(), ":8080"}},
+					},
+					"SMOKE_CHECKS": `[{"path":"/","expectedStatus":200,"bodyContains":"nginx"}]`,
+				},
+			},
+		})
+		template.HasResourceProperties(jsii.String("AWS::Lambda::Function"), map[string]interface{}{
+			"FunctionName": "bg-deploy-test-after-allow-traffic",
+			"Environment": map[string]interface{}{
+				"Variables": assertions.Match_ObjectLike(&map[string]interface{}{
+					"SMOKE_CHECKS": `[{"path":"/","expectedStatus":200}]`,
+				}),
+			},
+		})
+		// フックは結果を CodeDeploy に返し、CodeDeploy のサービスロールから呼び出される
+		template.HasResourceProperties(jsii.String("AWS::IAM::Policy"), map[string]interface{}{
+			"PolicyDocument": map[string]interface{}{
+				"Statement": assertions.Match_ArrayWith(&[]interface{}{
+					assertions.Match_ObjectLike(&map[string]interface{}{
+						"Action": "codedeploy:PutLifecycleEventHookExecutionStatus",
+					}),
+				}),
+			},
+		})
+		template.HasResourceProperties(jsii.String("AWS::IAM::Policy"), map[string]interface{}{
+			"PolicyDocument": map[string]interface{}{
+				"Statement": assertions.Match_ArrayWith(&[]interface{}{
+					assertions.Match_ObjectLike(&map[string]interface{}{
+						"Action": "lambda:InvokeFunction",
+					}),
+				}),
+			},
+			"Roles": []interface{}{map[string]interface{}{"Ref": assertions.Match_StringLikeRegexp(jsii.String("ServiceRole"))}},
+		})
+	})
+
+	t.Run("build generates appspec and taskdef from the service", func(t *testing.T) {
+		// プルリクエストの検証は既定では作成しない
+		template.ResourceCountIs(jsii.String("AWS::CodeBuild::Project"), jsii.Number(1))
+		template.HasResourceProperties(jsii.String("AWS::CodeBuild::Project"), map[string]interface{}{
+			"Source": assertions.Match_ObjectLike(&map[string]interface{}{
+				"Type":      "CODEPIPELINE",
+				"BuildSpec": assertions.Match_StringLikeRegexp(jsii.String("taskdef.json")),
+			}),
+			"Environment": assertions.Match_ObjectLike(&map[string]interface{}{
+				"EnvironmentVariables": assertions.Match_ArrayWith(&[]interface{}{
+					assertions.Match_ObjectLike(&map[string]interface{}{"Name": "APPSPEC"}),
+					assertions.Match_ObjectLike(&map[string]interface{}{"Name": "REPOSITORY_URI"}),
+					assertions.Match_ObjectLike(&map[string]interface{}{
+						"Name": "TASKDEF",
+						"Value": map[string]interface{}{
+							"Fn::Join": []interface{}{"", assertions.Match_ArrayWith(&[]interface{}{
+								assertions.Match_StringLikeRegexp(jsii.String(`"image":"<IMAGE1_NAME>".*"name":"nginx"`)),
+							})},
+						},
+					}),
+				}),
+			}),
+		})
+		template.HasResourceProperties(jsii.String("AWS::CodePipeline::Pipeline"), map[string]interface{}{
+			"Stages": assertions.Match_ArrayWith(&[]interface{}{
+				assertions.Match_ObjectLike(&map[string]interface{}{
+					"Name": "Deploy",
+					"Actions": []interface{}{
+						assertions.Match_ObjectLike(&map[string]interface{}{
+							"Configuration": assertions.Match_ObjectLike(&map[string]interface{}{
+								"AppSpecTemplatePath":        "appspec.yaml",
+								"TaskDefinitionTemplatePath": "taskdef.json",
+								"Image1ContainerName":        "IMAGE1_NAME",
+							}),
+						}),
+					},
+				}),
+			}),
+		})
+	})
+
+	t.Run("roles are scoped to the resources they use", func(t *testing.T) {
+		for _, roleName := range []string{"bg-deploy-test-codebuild-role", "bg-deploy-test-deploy-role", "bg-deploy-test-execution-role"} {
+			template.HasResourceProperties(jsii.String("AWS::IAM::Role"), map[string]interface{}{
+				"RoleName":          roleName,
+				"ManagedPolicyArns": assertions.Match_Absent(),
+			})
+		}
+		// CodeBuild は push 先のリポジトリと <RESOURCE_NAME>/ 以下の SSM パラメータだけを使う
+		template.HasResourceProperties(jsii.String("AWS::IAM::Policy"), map[string]interface{}{
+			"Roles": []interface{}{map[string]interface{}{"Ref": assertions.Match_StringLikeRegexp(jsii.String("^PipelineBuildRole"))}},
+			"PolicyDocument": map[string]interface{}{
+				"Statement": assertions.Match_ArrayWith(&[]interface{}{
+					assertions.Match_ObjectLike(&map[string]interface{}{
+						"Action":   assertions.Match_ArrayWith(&[]interface{}{"ecr:PutImage"}),
+						"Resource": map[string]interface{}{"Fn::Join": assertions.Match_AnyValue()},
+					}),
+					assertions.Match_ObjectLike(&map[string]interface{}{
+						"Action": "ssm:GetParameters",
+						"Resource": map[string]interface{}{"Fn::Join": []interface{}{"", assertions.Match_ArrayWith(&[]interface{}{
+							":ssm:ap-northeast-1:123456789012:parameter/bg-deploy-test/*",
+						})}},
+					}),
+				}),
+			},
+		})
+		template.HasResourceProperties(jsii.String("AWS::Logs::LogGroup"), map[string]interface{}{
+			"LogGroupName":    "/aws/codebuild/bg-deploy-test-codebuild-project",
+			"RetentionInDays": 30,
+		})
+		// CodeDeploy はデプロイするサービスのタスクセットと、タスク定義のロールだけを扱う
+		template.HasResourceProperties(jsii.String("AWS::IAM::Policy"), map[string]interface{}{
+			"Roles": []interface{}{map[string]interface{}{"Ref": assertions.Match_StringLikeRegexp(jsii.String("^PipelineDeploymentServiceRole"))}},
+			"PolicyDocument": map[string]interface{}{
+				"Statement": assertions.Match_ArrayWith(&[]interface{}{
+					assertions.Match_ObjectLike(&map[string]interface{}{
+						"Action":   assertions.Match_ArrayWith(&[]interface{}{"ecs:CreateTaskSet"}),
+						"Resource": []interface{}{map[string]interface{}{"Ref": assertions.Match_StringLikeRegexp(jsii.String("^Service"))}, assertions.Match_AnyValue()},
+					}),
+					assertions.Match_ObjectLike(&map[string]interface{}{
+						"Action":    "iam:PassRole",
+						"Condition": map[string]interface{}{"StringLike": map[string]interface{}{"iam:PassedToService": "ecs-tasks.amazonaws.com"}},
+					}),
+				}),
+			},
+		})
+	})
+
+	t.Run("pipeline stages", func(t *testing.T) {
+		template.HasResourceProperties(jsii.String("AWS::CodePipeline::Pipeline"), map[string]interface{}{
+			"Name": "bg-deploy-test-codepipeline",
+			"Stages": []interface{}{
+				assertions.Match_ObjectLike(&map[string]interface{}{"Name": "Source"}),
+				assertions.Match_ObjectLike(&map[string]interface{}{"Name": "Build"}),
+				assertions.Match_ObjectLike(&map[string]interface{}{"Name": "Approval"}),
+				assertions.Match_ObjectLike(&map[string]interface{}{"Name": "Deploy"}),
+			},
+		})
+	})
+}
+
+func TestBgDeploySampleStackRequiresConfig(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic when Config is nil")
+		}
+	}()
+
+	app := awscdk.NewApp(&awscdk.AppProps{
+		Context: testContext(),
+	})
+	NewBgDeploySampleStack(app, "bg-deploy-test", &BgDeploySampleStackProps{})
+}
+
+func TestConfigLoadRejectsMalformedEnvFile(t *testing.T) {
+	// GIVEN
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("RESOURCE_NAME=\"bg-deploy-test\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	// WHEN
+	_, err = config.Load()
+
+	// THEN
+	if err == nil || !strings.Contains(err.Error(), "failed to read .env") {
+		t.Fatalf("expected a .env parse error, got %v", err)
+	}
+}
+
+func TestBgDeploySampleStackPullRequestValidation(t *testing.T) {
+	// GIVEN
+	cfg := testConfig()
+	cfg.PullRequestValidation = true
+	cfg.PullRequestTestCommands = []string{"docker run --rm $IMAGE nginx -t"}
+
+	// WHEN
+	template := synthBgDeploySampleStack(t, cfg)
+
+	// THEN
+	template.ResourceCountIs(jsii.String("AWS::CodeBuild::Project"), jsii.Number(2))
+	template.HasResourceProperties(jsii.String("AWS::CodeBuild::Project"), map[string]interface{}{
+		"Name": "bg-deploy-test-pull-request",
+		"Source": assertions.Match_ObjectLike(&map[string]interface{}{
+			"Type":              "GITHUB",
+			"Location":          "https://github.com/example/bg-deploy-sample.git",
+			"ReportBuildStatus": true,
+			"BuildSpec":         assertions.Match_StringLikeRegexp(jsii.String("nginx -t")),
+			// パイプラインと同じ接続で GitHub にアクセスする
+			"Auth": map[string]interface{}{
+				"Type":     "CODECONNECTIONS",
+				"Resource": cfg.GitHubConnectionArn,
+			},
+		}),
+		"Triggers": map[string]interface{}{
+			"Webhook": true,
+			"FilterGroups": []interface{}{
+				assertions.Match_ArrayWith(&[]interface{}{
+					map[string]interface{}{"Type": "EVENT", "Pattern": "PULL_REQUEST_CREATED, PULL_REQUEST_UPDATED, PULL_REQUEST_REOPENED"},
+					map[string]interface{}{"Type": "BASE_REF", "Pattern": "refs/heads/main"},
+				}),
+			},
+		},
+	})
+}
+
+func TestBgDeploySampleStackNotifications(t *testing.T) {
+	// GIVEN
+	cfg := testConfig()
+	cfg.NotificationEmails = []string{"ops@example.com"}
+	cfg.NotificationWebhookSecret = "bg-deploy-test/pipeline-webhook"
+
+	// WHEN
+	template := synthBgDeploySampleStack(t, cfg)
+
+	// THEN
+	template.HasResourceProperties(jsii.String("AWS::SNS::Topic"), map[string]interface{}{
+		"TopicName": "bg-deploy-test-pipeline-notifications",
+	})
+	template.HasResourceProperties(jsii.String("AWS::SNS::Subscription"), map[string]interface{}{
+		"Protocol": "email",
+		"Endpoint": "ops@example.com",
+	})
+	template.HasResourceProperties(jsii.String("AWS::SNS::Subscription"), map[string]interface{}{
+		"Protocol": "lambda",
+	})
+	template.HasResourceProperties(jsii.String("AWS::Lambda::Function"), map[string]interface{}{
+		"FunctionName": "bg-deploy-test-pipeline-webhook",
+		"Environment": map[string]interface{}{
+			"Variables": map[string]interface{}{
+				"WEBHOOK_SECRET_ARN": assertions.Match_AnyValue(),
+			},
+		},
+	})
+	// Lambda はシークレットを読めるが、URL そのものはテンプレートに載らない
+	template.HasResourceProperties(jsii.String("AWS::IAM::Policy"), map[string]interface{}{
+		"PolicyDocument": map[string]interface{}{
+			"Statement": assertions.Match_ArrayWith(&[]interface{}{
+				assertions.Match_ObjectLike(&map[string]interface{}{
+					"Action": []interface{}{"secretsmanager:GetSecretValue", "secretsmanager:DescribeSecret"},
+				}),
+			}),
+		},
+	})
+	body, err := json.Marshal(template.ToJSON())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(body), "hooks.") || strings.Contains(string(body), "WEBHOOK_URL") {
+		t.Error("template must not contain the webhook URL")
+	}
+	template.HasResourceProperties(jsii.String("AWS::CodeStarNotifications::NotificationRule"), map[string]interface{}{
+		"Name":       "bg-deploy-test-pipeline",
+		"DetailType": "FULL",
+		"EventTypeIds": assertions.Match_ArrayWith(&[]interface{}{
+			"codepipeline-pipeline-pipeline-execution-failed",
+			"codepipeline-pipeline-manual-approval-needed",
+		}),
+		"Resource": assertions.Match_AnyValue(),
+		"Targets": []interface{}{
+			map[string]interface{}{"TargetType": "SNS", "TargetAddress": assertions.Match_AnyValue()},
+		},
+	})
+}
+
+func TestBgDeploySampleStackCompliance(t *testing.T) {
+	// GIVEN
+	cfg := testConfig()
+	cfg.PullRequestValidation = true
+	cfg.NotificationWebhookSecret = "bg-deploy-test/pipeline-webhook"
+	app := awscdk.NewApp(&awscdk.AppProps{
+		Context: testContext(),
+	})
+	awscdk.Aspects_Of(app).Add(security.NewComplianceChecks(), nil)
+
+	// WHEN
+	stack := NewBgDeploySampleStack(app, "bg-deploy-test", &BgDeploySampleStackProps{
+		StackProps: awscdk.StackProps{
+			Env: env(cfg),
+		},
+		Config: cfg,
+	})
+
+	// THEN
+	assertions.Annotations_FromStack(stack).HasNoError(jsii.String("*"), assertions.Match_AnyValue())
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"iaclib/deployment"
+	"iaclib/envconfig"
+)
+
+var (
+	connectionArnPattern = regexp.MustCompile(`^arn:aws[a-z-]*:(codestar-connections|codeconnections):[a-z0-9-]+:\d{12}:connection/[0-9a-f-]+$`)
+	secretNamePattern    = regexp.MustCompile(`^[A-Za-z0-9/_+=.@-]{1,512}$`)
+)
+
+// イメージの取得に必要なエンドポイント
+var requiredInterfaceEndpoints = []string{"ecr.api", "ecr.dkr"}
+
+// 本番トラフィックを切り替える前後に、テストリスナー経由で新しいタスクに送るリクエスト
+var (
+	defaultBeforeAllowTrafficChecks = []deployment.SmokeCheck{
+		{Path: "/", ExpectedStatus: 200},
+	}
+	defaultAfterAllowTrafficChecks = []deployment.SmokeCheck{
+		{Path: "/", ExpectedStatus: 200},
+	}
+)
+
+// プルリクエストの検証で、ビルドしたイメージ（$IMAGE）に対して実行するコマンド
+var defaultPullRequestTestCommands = []string{
+	// nginx の設定ファイルの構文を確認する
+	"docker run --rm $IMAGE nginx -t",
+}
+
+// Config はスタック全体で使う設定値
+type Config struct {
+	AccountID             string
+	Region                string
+	ResourceName          string
+	RepositoryName        string
+	GitHubConnectionArn   string
+	GitHubRepositoryOwner string
+	GitHubRepositoryName  string
+	GitHubBranchName      string
+	// NAT ゲートウェイを使わないため、プライベートサブネットから利用する AWS サービスはエンドポイント経由で接続する
+	InterfaceEndpoints []string
+	// CodeDeploy のライフサイクルフックで実行するスモークテスト（空の場合はフックを作成しない）
+	BeforeAllowTrafficChecks []deployment.SmokeCheck
+	AfterAllowTrafficChecks  []deployment.SmokeCheck
+	// true の場合、GitHubBranchName へのプルリクエストをビルドしてテストする CodeBuild プロジェクトを作成する
+	PullRequestValidation   bool
+	PullRequestTestCommands []string
+	// パイプラインの状態の変化と手動承認の通知先（どちらも空の場合は通知しない）。
+	// Webhook は URL を値に持つ Secrets Manager のシークレット名で指定する
+	NotificationEmails        []string
+	NotificationWebhookSecret string
+}
+
+// Load は .env とプロセスの環境変数から設定を読み込み、検証する
+func Load() (*Config, error) {
+	// 値はプロセスの環境変数、.env の順で探す（.env が無い場合は環境変数のみ）
+	file, err := envconfig.File(".env")
+	if err != nil {
+		return nil, envconfig.ValidationError([]error{err})
+	}
+	src := envconfig.NewSource(envconfig.Env(""), file)
+
+	cfg := &Config{
+		AccountID:             src.Get("ACCOUNT_ID"),
+		Region:                src.Get("REGION"),
+		ResourceName:          src.Get("RESOURCE_NAME"),
+		RepositoryName:        src.Get("REPOSITORY_NAME"),
+		GitHubConnectionArn:   src.Get("GITHUB_CONNECTION_ARN"),
+		GitHubRepositoryOwner: src.Get("GITHUB_REPOSITORY_OWNER"),
+		GitHubRepositoryName:  src.Get("GITHUB_REPOSITORY_NAME"),
+		GitHubBranchName:      src.Get("GITHUB_BRANCH_NAME"),
+		InterfaceEndpoints:    src.List("VPC_INTERFACE_ENDPOINTS", requiredInterfaceEndpoints),
+		PullRequestValidation: src.Bool("PULL_REQUEST_VALIDATION", false),
+
+		NotificationEmails:        src.List("NOTIFICATION_EMAILS", nil),
+		NotificationWebhookSecret: src.Get("NOTIFICATION_WEBHOOK_SECRET"),
+
+		BeforeAllowTrafficChecks: defaultBeforeAllowTrafficChecks,
+		AfterAllowTrafficChecks:  defaultAfterAllowTrafficChecks,
+		PullRequestTestCommands:  defaultPullRequestTestCommands,
+	}
+
+	errs := append(src.Errors(), cfg.problems()...)
+	// Webhook の URL はテンプレートに残るため、環境変数では受け付けない
+	if src.Get("NOTIFICATION_WEBHOOK_URL") != "" {
+		errs = append(errs, fmt.Errorf("NOTIFICATION_WEBHOOK_URL is no longer supported; store the URL in Secrets Manager and set NOTIFICATION_WEBHOOK_SECRET to the secret name"))
+	}
+	if err := envconfig.ValidationError(errs); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Validate は必須項目と各値の形式をまとめて検証する
+func (c *Config) Validate() error {
+	return envconfig.ValidationError(c.problems())
+}
+
+// problems は設定値の問題をすべて集める
+func (c *Config) problems() []error {
+	var errs []error
+
+	errs = append(errs, envconfig.Required(
+		envconfig.Field{Name: "ACCOUNT_ID", Value: c.AccountID},
+		envconfig.Field{Name: "REGION", Value: c.Region},
+		envconfig.Field{Name: "RESOURCE_NAME", Value: c.ResourceName},
+		envconfig.Field{Name: "REPOSITORY_NAME", Value: c.RepositoryName},
+		envconfig.Field{Name: "GITHUB_CONNECTION_ARN", Value: c.GitHubConnectionArn},
+		envconfig.Field{Name: "GITHUB_REPOSITORY_OWNER", Value: c.GitHubRepositoryOwner},
+		envconfig.Field{Name: "GITHUB_REPOSITORY_NAME", Value: c.GitHubRepositoryName},
+		envconfig.Field{Name: "GITHUB_BRANCH_NAME", Value: c.GitHubBranchName},
+	)...)
+	errs = append(errs, envconfig.CheckAccountID(c.AccountID)...)
+	errs = append(errs, envconfig.CheckRegion(c.Region)...)
+	errs = append(errs, envconfig.CheckResourceName(c.ResourceName)...)
+	if c.GitHubConnectionArn != "" && !connectionArnPattern.MatchString(c.GitHubConnectionArn) {
+		errs = append(errs, fmt.Errorf("GITHUB_CONNECTION_ARN must be a CodeStar Connections ARN, got %q", c.GitHubConnectionArn))
+	}
+
+	errs = append(errs, envconfig.CheckInterfaceEndpoints(c.InterfaceEndpoints, requiredInterfaceEndpoints)...)
+
+	for _, hook := range []struct {
+		name   string
+		checks []deployment.SmokeCheck
+	}{
+		{"BeforeAllowTraffic", c.BeforeAllowTrafficChecks},
+		{"AfterAllowTraffic", c.AfterAllowTrafficChecks},
+	} {
+		for _, check := range hook.checks {
+			if !strings.HasPrefix(check.Path, "/") {
+				errs = append(errs, fmt.Errorf("%s check path must start with /, got %q", hook.name, check.Path))
+			}
+			if check.ExpectedStatus != 0 && (check.ExpectedStatus < 100 || check.ExpectedStatus > 599) {
+				errs = append(errs, fmt.Errorf("%s check for %s must expect an HTTP status code, got %d", hook.name, check.Path, check.ExpectedStatus))
+			}
+		}
+	}
+
+	errs = append(errs, envconfig.CheckEmails("NOTIFICATION_EMAILS", c.NotificationEmails)...)
+	if c.NotificationWebhookSecret != "" && !secretNamePattern.MatchString(c.NotificationWebhookSecret) {
+		errs = append(errs, fmt.Errorf("NOTIFICATION_WEBHOOK_SECRET must be a Secrets Manager secret name, got %q", c.NotificationWebhookSecret))
+	}
+
+	return errs
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"testing"
+
+	"iaclib/snapshottest"
+)
+
+// スナップショットを更新する場合は `go test . -run Snapshot -update` を実行する
+func TestBgDeploySampleStackSnapshot(t *testing.T) {
+	template := synthBgDeploySampleStack(t, testConfig())
+
+	snapshottest.Assert(t, "bg-deploy-sample.template.json", template.ToJSON())
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsrds"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// Aurora PostgreSQL のクラスター（ライター 1 台とリーダー）
+func newAuroraCluster(scope constructs.Construct, props *PostgresDatabaseProps, resources *dbResources) awsrds.DatabaseCluster {
+	monitoring := props.Monitoring
+	production := props.Profile == ProfileProduction
+	aurora := props.Aurora
+
+	readerCount := props.auroraReaders()
+
+	instance := func(id string) awsrds.IClusterInstance {
+		identifier := jsii.String(resources.identifier + "-" + id)
+		if aurora.serverless() {
+			return awsrds.ClusterInstance_ServerlessV2(jsii.String(id), &awsrds.ServerlessV2ClusterInstanceProps{
+				InstanceIdentifier: identifier,
+				// リーダーはライターと同じ容量でスケールし、フェイルオーバー後も性能を保つ
+				ScaleWithWriter: jsii.Bool(id != "writer"),
+			})
+		}
+		return awsrds.ClusterInstance_Provisioned(jsii.String(id), &awsrds.ProvisionedClusterInstanceProps{
+			InstanceIdentifier: identifier,
+			InstanceType:       awsec2.NewInstanceType(jsii.String(props.InstanceType)),
+		})
+	}
+	var readers []awsrds.IClusterInstance
+	for i := 1; i <= readerCount; i++ {
+		readers = append(readers, instance(fmt.Sprintf("reader-%d", i)))
+	}
+
+	clusterProps := &awsrds.DatabaseClusterProps{
+		ClusterIdentifier:   jsii.String(resources.identifier),
+		DefaultDatabaseName: jsii.String(props.DatabaseName),
+		Engine:              resources.engine.(awsrds.IClusterEngine),
+		Writer:              instance("writer"),
+		Readers:             &readers,
+		Vpc:                 props.Vpc,
+		SecurityGroups:      &[]awsec2.ISecurityGroup{resources.securityGroup},
+		SubnetGroup:         resources.subnetGroup,
+		// パスワードは Secrets Manager で生成する
+		Credentials: awsrds.Credentials_FromGeneratedSecret(jsii.String(props.Username), &awsrds.CredentialsBaseOptions{
+			SecretName: jsii.String(props.ResourceName + "-db-credentials"),
+		}),
+
+		// ストレージは自動で拡張される
+		StorageEncrypted: jsii.Bool(true),
+
+		// バックアップ設定（クラスターには自動バックアップを残す設定が無いため、production では最終スナップショットで保護する）
+		Backup: &awsrds.BackupProps{
+			Retention: awscdk.Duration_Days(jsii.Number(props.BackupRetentionDays)),
+		},
+		DeletionProtection: jsii.Bool(production),
+
+		// メンテナンス設定
+		AutoMinorVersionUpgrade: jsii.Bool(true),
+
+		// クラスターのパラメータグループ
+		ParameterGroup: resources.parameterGroup,
+
+		// モニタリング設定（拡張モニタリングは full の場合のみ）
+		MonitoringInterval:        awscdk.Duration_Seconds(jsii.Number(monitoring.intervalSeconds())),
+		MonitoringRole:            resources.monitoringRole,
+		EnablePerformanceInsights: jsii.Bool(monitoring.enabled()),
+
+		// ログ設定（保持期間は作成済みのロググループで設定する）
+		CloudwatchLogsExports: jsii.Strings(monitoring.logExports(props.Engine)...),
+
+		// 削除時の設定（production ではスナップショットを残す）
+		RemovalPolicy: resources.removalPolicy,
+	}
+	if aurora.serverless() {
+		clusterProps.ServerlessV2MinCapacity = jsii.Number(aurora.ServerlessV2MinCapacity)
+		clusterProps.ServerlessV2MaxCapacity = jsii.Number(aurora.ServerlessV2MaxCapacity)
+	}
+	if props.BackupWindow != "" {
+		clusterProps.Backup.PreferredWindow = jsii.String(props.BackupWindow)
+	}
+	if props.MaintenanceWindow != "" {
+		clusterProps.PreferredMaintenanceWindow = jsii.String(props.MaintenanceWindow)
+	}
+	if monitoring.enabled() {
+		clusterProps.PerformanceInsightRetention = monitoring.performanceInsightRetention()
+	}
+	return awsrds.NewDatabaseCluster(scope, jsii.String("Cluster"), clusterProps)
+}
+
+// マルチ AZ にするには、ライターと別の AZ にリーダーが必要
+func (p *PostgresDatabaseProps) auroraReaders() int {
+	if p.MultiAz && p.Aurora.Readers < 1 {
+		return 1
+	}
+	return p.Aurora.Readers
+}
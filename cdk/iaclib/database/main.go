@@ -0,0 +1,437 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscloudwatch"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslogs"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsrds"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awssecretsmanager"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// Engine は DB のエンジンと構成
+type Engine string
+
+const (
+	// RDS for PostgreSQL の単一のインスタンス（既定）
+	EnginePostgres Engine = "postgres"
+	// Aurora PostgreSQL のクラスター（プロビジョンドまたは Serverless v2）
+	EngineAuroraPostgres Engine = "aurora-postgresql"
+)
+
+// Engines は指定できるエンジン
+var Engines = []Engine{EnginePostgres, EngineAuroraPostgres}
+
+// AuroraProps は Aurora PostgreSQL のクラスターの構成（Engine が aurora-postgresql の場合に使う）
+type AuroraProps struct {
+	// リーダーインスタンスの数（マルチ AZ の場合は 1 以上にする）
+	Readers int
+	// Serverless v2 の最小 / 最大 ACU（ServerlessV2MaxCapacity が 0 の場合は InstanceType のプロビジョンドインスタンス）
+	ServerlessV2MinCapacity float64
+	ServerlessV2MaxCapacity float64
+}
+
+func (a AuroraProps) serverless() bool {
+	return a.ServerlessV2MaxCapacity > 0
+}
+
+// Profile は DB の可用性と削除時の保護の設定の組み合わせ
+type Profile string
+
+const (
+	// シングル AZ で削除保護なし、スタックの削除時に DB と自動バックアップも削除する（既定）
+	ProfileFreeTier Profile = "free-tier"
+	// マルチ AZ で削除保護あり、スタックの削除時はスナップショットと自動バックアップを残す
+	ProfileProduction Profile = "production"
+)
+
+// Profiles は指定できるプロファイル
+var Profiles = []Profile{ProfileFreeTier, ProfileProduction}
+
+// MonitoringLevel は RDS の監視の詳細さ
+type MonitoringLevel string
+
+const (
+	// Performance Insights、拡張モニタリング、ログのエクスポートをすべて無効にする（無料利用枠）
+	MonitoringLevelOff MonitoringLevel = "off"
+	// Performance Insights（保持期間 7 日の無料枠）と PostgreSQL のログのエクスポート
+	MonitoringLevelBasic MonitoringLevel = "basic"
+	// basic に加えて拡張モニタリング、アップグレードのログのエクスポート、Performance Insights の保持期間の指定
+	MonitoringLevelFull MonitoringLevel = "full"
+)
+
+// MonitoringLevels は指定できる監視の詳細さ
+var MonitoringLevels = []MonitoringLevel{MonitoringLevelOff, MonitoringLevelBasic, MonitoringLevelFull}
+
+// MonitoringProps は RDS の監視の設定
+type MonitoringProps struct {
+	// 空の場合は off
+	Level MonitoringLevel
+	// full の場合の Performance Insights の保持期間（空の場合は 7 日）
+	PerformanceInsightRetention awsrds.PerformanceInsightRetention
+	// full の場合の拡張モニタリングの間隔（秒。0 の場合は 60）
+	EnhancedMonitoringIntervalSeconds int
+	// エクスポートした DB のログの保持期間（空の場合は 1 か月）
+	LogRetention awslogs.RetentionDays
+	// この時間（ミリ秒）以上かかったクエリをログに出力する（off の場合と 0 の場合は出力しない）
+	SlowQueryMillis int
+}
+
+type PostgresDatabaseProps struct {
+	// 各リソースの名前の接頭辞
+	ResourceName string
+	// isolated サブネットを持つ VPC
+	Vpc awsec2.IVpc
+	// DB への接続を許可するセキュリティグループ（ECS サービスなど）
+	ClientSecurityGroups []awsec2.ISecurityGroup
+	DatabaseName         string
+	Username             string
+	// 空の場合は postgres
+	Engine Engine
+	Aurora AuroraProps
+	// 例: t3.micro（Aurora のプロビジョンドの場合は t4g.medium など）
+	InstanceType string
+	// マルチ AZ（Aurora の場合はリーダーを最低 1 台置く）。production では true にする
+	MultiAz             bool
+	BackupRetentionDays int
+	// パスワードの自動ローテーション間隔（0 の場合は無効）
+	RotationDays int
+	// 空の場合は free-tier
+	Profile Profile
+	// インスタンス（Aurora の場合はクラスター）ID。空の場合は <ResourceName>-db。
+	// 変更するとインスタンスが置き換えられるため、既存の DB の ID を引き継ぐ場合に指定する
+	Identifier string
+	// 自動バックアップとメンテナンスの時間帯（UTC。例: 18:00-19:00、sun:19:00-sun:20:00。空の場合は AWS が決める）
+	BackupWindow      string
+	MaintenanceWindow string
+	// Performance Insights、拡張モニタリング、ログのエクスポート（既定は無効）
+	Monitoring MonitoringProps
+	// 指定した場合は RDS Proxy を置き、ClientSecurityGroups からはプロキシ経由で接続させる
+	Proxy *ProxyProps
+}
+
+type PostgresDatabase struct {
+	constructs.Construct
+	// Engine が postgres の場合のインスタンス
+	Instance awsrds.DatabaseInstance
+	// Engine が aurora-postgresql の場合のクラスター
+	Cluster awsrds.DatabaseCluster
+	// 書き込み用と読み取り用のエンドポイント（単一のインスタンスの場合はどちらもインスタンスのエンドポイント。
+	// RDS Proxy を使う場合はプロキシのエンドポイント）
+	Endpoint       awsrds.Endpoint
+	ReaderEndpoint awsrds.Endpoint
+	SecurityGroup  awsec2.ISecurityGroup
+	// RDS Proxy とそのセキュリティグループ（Proxy を指定した場合のみ）
+	Proxy              awsrds.DatabaseProxy
+	ProxySecurityGroup awsec2.ISecurityGroup
+	// DB の認証情報（username / password）
+	Secret awssecretsmanager.ISecret
+	// 拡張モニタリングのロール（監視が full の場合のみ）
+	MonitoringRole awsiam.IRole
+	// エクスポートした DB のログのロググループ（キーは postgresql / upgrade）
+	LogGroups map[string]awslogs.ILogGroup
+}
+
+func NewPostgresDatabase(scope constructs.Construct, id string, props *PostgresDatabaseProps) *PostgresDatabase {
+	this := constructs.NewConstruct(scope, &id)
+	vpc := props.Vpc
+	resourceName := props.ResourceName
+	// 同じアカウントに複数のスタックを作れるよう、インスタンス（クラスター）ID にリソース名を含める
+	identifier := resourceName + "-db"
+	if props.Identifier != "" {
+		identifier = props.Identifier
+	}
+	monitoring := props.Monitoring
+	production := props.Profile == ProfileProduction
+	aurora := props.Engine == EngineAuroraPostgres
+
+	// 本番ではスタックを削除しても DB のスナップショットとログを残す
+	removalPolicy := awscdk.RemovalPolicy_DESTROY
+	logRemovalPolicy := awscdk.RemovalPolicy_DESTROY
+	if production {
+		removalPolicy = awscdk.RemovalPolicy_SNAPSHOT
+		logRemovalPolicy = awscdk.RemovalPolicy_RETAIN
+	}
+
+	// sg for RDS（DB と同じスコープに置き、接続元のスタックに依存させない）
+	securityGroup := awsec2.NewSecurityGroup(this, jsii.String("SecurityGroup"), &awsec2.SecurityGroupProps{
+		SecurityGroupName: jsii.String(resourceName + "-sg-rds"),
+		Vpc:               vpc,
+		AllowAllOutbound:  jsii.Bool(false),
+	})
+	// RDS Proxy を使う場合、クライアントからの通信はプロキシのセキュリティグループで許可する
+	if props.Proxy == nil {
+		for _, client := range props.ClientSecurityGroups {
+			securityGroup.AddIngressRule(client, awsec2.Port_Tcp(jsii.Number(5432)), jsii.String("PostgreSQL from client"), jsii.Bool(false))
+		}
+	}
+
+	// DB サブネットグループの作成
+	subnetGroup := awsrds.NewSubnetGroup(this, jsii.String("SubnetGroup"), &awsrds.SubnetGroupProps{
+		Description: jsii.String("Subnet group for RDS"),
+		Vpc:         vpc,
+		VpcSubnets: &awsec2.SubnetSelection{
+			SubnetType: awsec2.SubnetType_PRIVATE_ISOLATED,
+		},
+	})
+
+	// PostgreSQL パラメータグループの作成
+	parameters := map[string]*string{
+		"shared_preload_libraries": jsii.String("pg_stat_statements"),
+	}
+	if monitoring.enabled() && monitoring.SlowQueryMillis > 0 {
+		// 遅いクエリとロック待ちを postgresql のログに出力する
+		parameters["log_min_duration_statement"] = jsii.String(strconv.Itoa(monitoring.SlowQueryMillis))
+		parameters["log_lock_waits"] = jsii.String("1")
+	}
+	var engine awsrds.IEngine = awsrds.DatabaseInstanceEngine_Postgres(&awsrds.PostgresInstanceEngineProps{
+		Version: awsrds.PostgresEngineVersion_VER_16_4(),
+	})
+	logGroupPrefix := "/aws/rds/instance/"
+	if aurora {
+		engine = awsrds.DatabaseClusterEngine_AuroraPostgres(&awsrds.AuroraPostgresClusterEngineProps{
+			Version: awsrds.AuroraPostgresEngineVersion_VER_16_4(),
+		})
+		logGroupPrefix = "/aws/rds/cluster/"
+	}
+	// Aurora ではクラスターのパラメータグループになる
+	parameterGroup := awsrds.NewParameterGroup(this, jsii.String("ParameterGroup"), &awsrds.ParameterGroupProps{
+		Engine:     engine,
+		Parameters: &parameters,
+	})
+
+	// エクスポートするログのロググループ（RDS が作成すると保持期間が無期限になるため、先に作成しておく）
+	logGroups := map[string]awslogs.ILogGroup{}
+	for _, logType := range monitoring.logExports(props.Engine) {
+		retention := monitoring.LogRetention
+		if retention == "" {
+			retention = awslogs.RetentionDays_ONE_MONTH
+		}
+		logGroups[logType] = awslogs.NewLogGroup(this, jsii.String(logGroupID(logType)), &awslogs.LogGroupProps{
+			LogGroupName:  jsii.String(fmt.Sprintf("%s%s/%s", logGroupPrefix, identifier, logType)),
+			Retention:     retention,
+			RemovalPolicy: logRemovalPolicy,
+		})
+	}
+
+	// 拡張モニタリングのロール（AWS 管理ポリシーの代わりに RDSOSMetrics のロググループに限る）
+	var monitoringRole awsiam.Role
+	if monitoring.Level == MonitoringLevelFull {
+		monitoringRole = awsiam.NewRole(this, jsii.String("MonitoringRole"), &awsiam.RoleProps{
+			RoleName:  jsii.String(resourceName + "-rds-monitoring-role"),
+			AssumedBy: awsiam.NewServicePrincipal(jsii.String("monitoring.rds.amazonaws.com"), nil),
+		})
+		metricsLogGroup := awscdk.Stack_Of(this).FormatArn(&awscdk.ArnComponents{
+			Service:      jsii.String("logs"),
+			Resource:     jsii.String("log-group"),
+			ResourceName: jsii.String("RDSOSMetrics"),
+			ArnFormat:    awscdk.ArnFormat_COLON_RESOURCE_NAME,
+		})
+		monitoringRole.AddToPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+			Actions:   jsii.Strings("logs:CreateLogGroup", "logs:PutRetentionPolicy"),
+			Resources: &[]*string{metricsLogGroup},
+		}))
+		monitoringRole.AddToPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+			Actions:   jsii.Strings("logs:CreateLogStream", "logs:PutLogEvents", "logs:DescribeLogStreams", "logs:GetLogEvents"),
+			Resources: jsii.Strings(*metricsLogGroup + ":log-stream:*"),
+		}))
+	}
+
+	database := &PostgresDatabase{
+		Construct:      this,
+		SecurityGroup:  securityGroup,
+		MonitoringRole: monitoringRole,
+		LogGroups:      logGroups,
+	}
+	resources := &dbResources{
+		identifier:     identifier,
+		engine:         engine,
+		securityGroup:  securityGroup,
+		subnetGroup:    subnetGroup,
+		parameterGroup: parameterGroup,
+		monitoringRole: monitoringRole,
+		removalPolicy:  removalPolicy,
+	}
+	var db rotatable
+	var proxyTarget awsrds.ProxyTarget
+	if aurora {
+		cluster := newAuroraCluster(this, props, resources)
+		database.Cluster = cluster
+		database.Endpoint = cluster.ClusterEndpoint()
+		database.ReaderEndpoint = cluster.ClusterReadEndpoint()
+		database.Secret = cluster.Secret()
+		db = cluster
+		proxyTarget = awsrds.ProxyTarget_FromCluster(cluster)
+	} else {
+		instance := newInstance(this, props, resources)
+		database.Instance = instance
+		database.Endpoint = instance.InstanceEndpoint()
+		database.ReaderEndpoint = instance.InstanceEndpoint()
+		database.Secret = instance.Secret()
+		db = instance
+		proxyTarget = awsrds.ProxyTarget_FromInstance(instance)
+	}
+
+	if props.Proxy != nil {
+		proxy := newProxy(this, props, proxyTarget, database.Secret, aurora && props.auroraReaders() > 0)
+		database.Proxy = proxy.proxy
+		database.ProxySecurityGroup = proxy.securityGroup
+		database.Endpoint = proxy.endpoint
+		database.ReaderEndpoint = proxy.readerEndpoint
+	}
+
+	for _, logGroup := range logGroups {
+		db.Node().AddDependency(logGroup)
+	}
+
+	// パスワードの自動ローテーション
+	if props.RotationDays > 0 {
+		db.AddRotationSingleUser(&awsrds.RotationSingleUserOptions{
+			AutomaticallyAfter: awscdk.Duration_Days(jsii.Number(props.RotationDays)),
+			VpcSubnets: &awsec2.SubnetSelection{
+				SubnetType: awsec2.SubnetType_PRIVATE_ISOLATED,
+			},
+		})
+	}
+
+	return database
+}
+
+// DB サブネットグループなど、インスタンスとクラスターで共通のリソース
+type dbResources struct {
+	identifier     string
+	engine         awsrds.IEngine
+	securityGroup  awsec2.ISecurityGroup
+	subnetGroup    awsrds.ISubnetGroup
+	parameterGroup awsrds.IParameterGroup
+	monitoringRole awsiam.IRole
+	removalPolicy  awscdk.RemovalPolicy
+}
+
+// インスタンスとクラスターに共通の操作
+type rotatable interface {
+	constructs.IConstruct
+	AddRotationSingleUser(options *awsrds.RotationSingleUserOptions) awssecretsmanager.SecretRotation
+}
+
+// RDS for PostgreSQL のインスタンス
+func newInstance(scope constructs.Construct, props *PostgresDatabaseProps, resources *dbResources) awsrds.DatabaseInstance {
+	monitoring := props.Monitoring
+	production := props.Profile == ProfileProduction
+
+	instanceProps := &awsrds.DatabaseInstanceProps{
+		DatabaseName:       jsii.String(props.DatabaseName),
+		InstanceIdentifier: jsii.String(resources.identifier),
+		Engine:             resources.engine.(awsrds.IInstanceEngine),
+		InstanceType:       awsec2.NewInstanceType(jsii.String(props.InstanceType)),
+		Vpc:                props.Vpc,
+		SecurityGroups:     &[]awsec2.ISecurityGroup{resources.securityGroup},
+		SubnetGroup:        resources.subnetGroup,
+		// パスワードは Secrets Manager で生成する
+		Credentials: awsrds.Credentials_FromGeneratedSecret(jsii.String(props.Username), &awsrds.CredentialsBaseOptions{
+			SecretName: jsii.String(props.ResourceName + "-db-credentials"),
+		}),
+
+		// ストレージ設定（無料利用枠：20GB）
+		AllocatedStorage:    jsii.Number(20),
+		StorageType:         awsrds.StorageType_GP3,
+		StorageEncrypted:    jsii.Bool(true),
+		MaxAllocatedStorage: jsii.Number(1000), // 自動スケーリング上限
+
+		// バックアップ設定（production では DB を削除しても自動バックアップを残す）
+		BackupRetention:        awscdk.Duration_Days(jsii.Number(props.BackupRetentionDays)),
+		DeleteAutomatedBackups: jsii.Bool(!production),
+		DeletionProtection:     jsii.Bool(production),
+
+		// メンテナンス設定
+		AutoMinorVersionUpgrade: jsii.Bool(true),
+
+		// マルチAZ設定
+		MultiAz: jsii.Bool(props.MultiAz),
+
+		// パラメータグループ
+		ParameterGroup: resources.parameterGroup,
+
+		// モニタリング設定（拡張モニタリングは full の場合のみ）
+		MonitoringInterval:        awscdk.Duration_Seconds(jsii.Number(monitoring.intervalSeconds())),
+		MonitoringRole:            resources.monitoringRole,
+		EnablePerformanceInsights: jsii.Bool(monitoring.enabled()),
+
+		// ログ設定（保持期間は作成済みのロググループで設定する）
+		CloudwatchLogsExports: jsii.Strings(monitoring.logExports(props.Engine)...),
+
+		// 削除時の設定（production ではスナップショットを残す）
+		RemovalPolicy: resources.removalPolicy,
+	}
+	if props.BackupWindow != "" {
+		instanceProps.PreferredBackupWindow = jsii.String(props.BackupWindow)
+	}
+	if props.MaintenanceWindow != "" {
+		instanceProps.PreferredMaintenanceWindow = jsii.String(props.MaintenanceWindow)
+	}
+	if monitoring.enabled() {
+		instanceProps.PerformanceInsightRetention = monitoring.performanceInsightRetention()
+	}
+	return awsrds.NewDatabaseInstance(scope, jsii.String("Instance"), instanceProps)
+}
+
+// Metric は DB インスタンス（Aurora の場合はクラスター）の AWS/RDS のメトリクスを返す
+func (d *PostgresDatabase) Metric(metricName string, props *awscloudwatch.MetricOptions) awscloudwatch.Metric {
+	if d.Cluster != nil {
+		return d.Cluster.Metric(jsii.String(metricName), props)
+	}
+	return d.Instance.Metric(jsii.String(metricName), props)
+}
+
+// MetricFreeStorage は空きストレージ（バイト）のメトリクスを返す。Aurora のストレージは自動で拡張されるため、インスタンスのローカルストレージを監視する
+func (d *PostgresDatabase) MetricFreeStorage(props *awscloudwatch.MetricOptions) awscloudwatch.Metric {
+	if d.Cluster != nil {
+		return d.Metric("FreeLocalStorage", props)
+	}
+	return d.Metric("FreeStorageSpace", props)
+}
+
+func (m MonitoringProps) enabled() bool {
+	return m.Level == MonitoringLevelBasic || m.Level == MonitoringLevelFull
+}
+
+func (m MonitoringProps) intervalSeconds() int {
+	if m.Level != MonitoringLevelFull {
+		return 0
+	}
+	if m.EnhancedMonitoringIntervalSeconds == 0 {
+		return 60
+	}
+	return m.EnhancedMonitoringIntervalSeconds
+}
+
+// basic と、full で保持期間を指定しない場合は無料枠の 7 日
+func (m MonitoringProps) performanceInsightRetention() awsrds.PerformanceInsightRetention {
+	if m.Level != MonitoringLevelFull || m.PerformanceInsightRetention == "" {
+		return awsrds.PerformanceInsightRetention_DEFAULT
+	}
+	return m.PerformanceInsightRetention
+}
+
+// Aurora PostgreSQL がエクスポートできるのは postgresql のログのみ
+func (m MonitoringProps) logExports(engine Engine) []string {
+	switch {
+	case m.Level == MonitoringLevelBasic, m.Level == MonitoringLevelFull && engine == EngineAuroraPostgres:
+		return []string{"postgresql"}
+	case m.Level == MonitoringLevelFull:
+		return []string{"postgresql", "upgrade"}
+	}
+	return []string{}
+}
+
+// postgresql → PostgresqlLogGroup
+func logGroupID(logType string) string {
+	return strings.ToUpper(logType[:1]) + logType[1:] + "LogGroup"
+}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsrds"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awssecretsmanager"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// ProxyProps は RDS Proxy の設定
+type ProxyProps struct {
+	// DB の max_connections のうち、プロキシが使う接続の割合（%。0 の場合は 100）
+	MaxConnectionsPercent int
+}
+
+// RDS Proxy の書き込み用と読み取り用のエンドポイント
+type proxyEndpoints struct {
+	proxy          awsrds.DatabaseProxy
+	securityGroup  awsec2.ISecurityGroup
+	endpoint       awsrds.Endpoint
+	readerEndpoint awsrds.Endpoint
+}
+
+// isolated サブネットに RDS Proxy を置き、クライアントからの接続はプロキシ経由にする
+func newProxy(scope constructs.Construct, props *PostgresDatabaseProps, target awsrds.ProxyTarget, secret awssecretsmanager.ISecret, readOnly bool) *proxyEndpoints {
+	resourceName := props.ResourceName
+	subnets := &awsec2.SubnetSelection{
+		SubnetType: awsec2.SubnetType_PRIVATE_ISOLATED,
+	}
+	port := awsec2.Port_Tcp(jsii.Number(5432))
+
+	// sg for RDS Proxy（クライアント → プロキシ → DB の順にだけ通す）
+	securityGroup := awsec2.NewSecurityGroup(scope, jsii.String("ProxySecurityGroup"), &awsec2.SecurityGroupProps{
+		SecurityGroupName: jsii.String(resourceName + "-sg-rds-proxy"),
+		Vpc:               props.Vpc,
+		AllowAllOutbound:  jsii.Bool(false),
+	})
+	for _, client := range props.ClientSecurityGroups {
+		securityGroup.AddIngressRule(client, port, jsii.String("PostgreSQL from client"), jsii.Bool(false))
+	}
+	// プロキシから DB への通信は DatabaseProxy が target の接続設定に追加する
+	// プロキシは DB の認証情報を Secrets Manager の VPC エンドポイント経由で取得する
+	securityGroup.AddEgressRule(awsec2.Peer_Ipv4(props.Vpc.VpcCidrBlock()), awsec2.Port_Tcp(jsii.Number(443)), jsii.String("Secrets Manager endpoint"), jsii.Bool(false))
+
+	proxyProps := &awsrds.DatabaseProxyProps{
+		DbProxyName:    jsii.String(resourceName + "-db-proxy"),
+		ProxyTarget:    target,
+		Secrets:        &[]awssecretsmanager.ISecret{secret},
+		Vpc:            props.Vpc,
+		VpcSubnets:     subnets,
+		SecurityGroups: &[]awsec2.ISecurityGroup{securityGroup},
+		// クライアントは IAM 認証のトークンで TLS 接続する
+		IamAuth:    jsii.Bool(true),
+		RequireTLS: jsii.Bool(true),
+	}
+	if props.Proxy.MaxConnectionsPercent > 0 {
+		proxyProps.MaxConnectionsPercent = jsii.Number(props.Proxy.MaxConnectionsPercent)
+	}
+	proxy := awsrds.NewDatabaseProxy(scope, jsii.String("Proxy"), proxyProps)
+
+	endpoint := awsrds.NewEndpoint(proxy.Endpoint(), jsii.Number(5432))
+	result := &proxyEndpoints{
+		proxy:          proxy,
+		securityGroup:  securityGroup,
+		endpoint:       endpoint,
+		readerEndpoint: endpoint,
+	}
+
+	// Aurora のリーダーに振り分ける読み取り専用のエンドポイント
+	if readOnly {
+		reader := awsrds.NewCfnDBProxyEndpoint(scope, jsii.String("ProxyReaderEndpoint"), &awsrds.CfnDBProxyEndpointProps{
+			DbProxyEndpointName: jsii.String(resourceName + "-db-proxy-reader"),
+			DbProxyName:         proxy.DbProxyName(),
+			VpcSubnetIds:        props.Vpc.SelectSubnets(subnets).SubnetIds,
+			VpcSecurityGroupIds: jsii.Strings(*securityGroup.SecurityGroupId()),
+			TargetRole:          jsii.String("READ_ONLY"),
+		})
+		result.readerEndpoint = awsrds.NewEndpoint(reader.AttrEndpoint(), jsii.Number(5432))
+	}
+
+	return result
+}
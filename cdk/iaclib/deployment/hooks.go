@@ -0,0 +1,79 @@
+package deployment
+
+import (
+	_ "embed"
+	"encoding/json"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscodedeploy"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslambda"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslogs"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+//go:embed smoke_check.js
+var smokeCheckSource string
+
+// SmokeTestProps は本番トラフィックを切り替える前後にテストリスナーで実行するスモークテスト。
+// フックの Lambda は <ResourceName>-before-allow-traffic / <ResourceName>-after-allow-traffic という名前で作成し、
+// appspec の Hooks から参照する
+type SmokeTestProps struct {
+	// テストリスナーの URL（例: http://<ALB の DNS 名>:8080）。Lambda は VPC の外から接続する
+	TestEndpoint string
+	// 空の場合はそのフックを作成しない
+	BeforeAllowTraffic []SmokeCheck
+	AfterAllowTraffic  []SmokeCheck
+}
+
+// SmokeCheck は1つの HTTP リクエストと、期待するレスポンス
+type SmokeCheck struct {
+	// 例: /up
+	Path string `json:"path"`
+	// 0 の場合は 200
+	ExpectedStatus int `json:"expectedStatus"`
+	// 空でない場合、レスポンスボディにこの文字列が含まれることを確認する
+	BodyContains string `json:"bodyContains,omitempty"`
+}
+
+// スモークテストを実行し、結果を CodeDeploy に返す Lambda を作成する
+func newSmokeTestHook(scope constructs.Construct, id string, functionName string, endpoint string, checks []SmokeCheck, deploymentGroup awscodedeploy.IEcsDeploymentGroup, codeDeployRole awsiam.IRole) awslambda.IFunction {
+	normalized := make([]SmokeCheck, 0, len(checks))
+	for _, check := range checks {
+		if check.ExpectedStatus == 0 {
+			check.ExpectedStatus = 200
+		}
+		normalized = append(normalized, check)
+	}
+	// SmokeCheck は文字列と数値だけなので失敗しない
+	encoded, _ := json.Marshal(normalized)
+
+	logGroup := awslogs.NewLogGroup(scope, jsii.String(id+"LogGroup"), &awslogs.LogGroupProps{
+		LogGroupName:  jsii.String("/aws/lambda/" + functionName),
+		RemovalPolicy: awscdk.RemovalPolicy_DESTROY,
+		Retention:     awslogs.RetentionDays_ONE_MONTH,
+	})
+
+	hook := awslambda.NewFunction(scope, jsii.String(id), &awslambda.FunctionProps{
+		FunctionName: jsii.String(functionName),
+		Runtime:      awslambda.Runtime_NODEJS_20_X(),
+		Handler:      jsii.String("index.handler"),
+		Code:         awslambda.Code_FromInline(jsii.String(smokeCheckSource)),
+		Timeout:      awscdk.Duration_Minutes(jsii.Number(5)),
+		Environment: &map[string]*string{
+			"BASE_URL":     jsii.String(endpoint),
+			"SMOKE_CHECKS": jsii.String(string(encoded)),
+		},
+		LogGroup: logGroup,
+	})
+
+	hook.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("codedeploy:PutLifecycleEventHookExecutionStatus"),
+		Resources: jsii.Strings(*deploymentGroup.DeploymentGroupArn()),
+	}))
+	// CodeDeploy がデプロイ中にフックを呼び出す
+	hook.GrantInvoke(codeDeployRole)
+
+	return hook
+}
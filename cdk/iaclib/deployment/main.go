@@ -0,0 +1,262 @@
+package deployment
+
+import (
+	"iaclib/monitoring"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscloudwatch"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscodedeploy"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsecs"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awselasticloadbalancingv2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslambda"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+type BlueGreenDeploymentProps struct {
+	// 各リソースの名前の接頭辞
+	ResourceName string
+	// CODE_DEPLOY コントローラーの ECS サービス
+	Service awsecs.IBaseService
+	// サービスのタスク定義（CodeDeploy にはこのタスクロールと実行ロールだけを渡せるようにする）
+	TaskDefinition awsecs.FargateTaskDefinition
+	// 本番リスナーの tg1 (blue) とテストリスナーの tg2 (green) を入れ替える
+	BlueTargetGroup  awselasticloadbalancingv2.IApplicationTargetGroup
+	GreenTargetGroup awselasticloadbalancingv2.IApplicationTargetGroup
+	ProdListener     awselasticloadbalancingv2.IApplicationListener
+	TestListener     awselasticloadbalancingv2.IApplicationListener
+	// 本番トラフィックの切り替え方（指定しない場合は 1 分ごとに 10% ずつ）
+	TrafficShifting *TrafficShiftingProps
+	// 指定した場合、ターゲットグループがしきい値を超えたらデプロイを自動でロールバックする
+	Alarms *monitoring.TargetGroupThresholds
+	// 切り替え後、blue のタスクを終了するまでの待ち時間（指定しない場合はすぐに終了する）
+	TerminationWaitTime awscdk.Duration
+	// 指定した場合、ライフサイクルフックの Lambda でスモークテストを実行する
+	SmokeTests *SmokeTestProps
+}
+
+// TrafficShiftingType は本番トラフィックを green に切り替える方式
+type TrafficShiftingType string
+
+const (
+	// 全トラフィックを一度に切り替える
+	TrafficShiftingAllAtOnce TrafficShiftingType = "all-at-once"
+	// IntervalMinutes ごとに Percentage % ずつ切り替える
+	TrafficShiftingLinear TrafficShiftingType = "linear"
+	// 最初に Percentage % を切り替え、IntervalMinutes 後に残りを切り替える
+	TrafficShiftingCanary TrafficShiftingType = "canary"
+	// DeploymentConfigName で指定した既存のデプロイ設定を使う
+	TrafficShiftingCustom TrafficShiftingType = "custom"
+)
+
+// 指定できるトラフィックの切り替え方式
+var TrafficShiftingTypes = []TrafficShiftingType{
+	TrafficShiftingAllAtOnce,
+	TrafficShiftingLinear,
+	TrafficShiftingCanary,
+	TrafficShiftingCustom,
+}
+
+type TrafficShiftingProps struct {
+	Type            TrafficShiftingType
+	Percentage      int
+	IntervalMinutes int
+	// custom の場合のデプロイ設定名
+	DeploymentConfigName string
+}
+
+type BlueGreenDeployment struct {
+	constructs.Construct
+	Application     awscodedeploy.EcsApplication
+	DeploymentGroup awscodedeploy.EcsDeploymentGroup
+	ServiceRole     awsiam.Role
+	// ロールバックに使うアラーム
+	Alarms []awscloudwatch.Alarm
+	// appspec の BeforeAllowTraffic / AfterAllowTraffic フック（作成しない場合は nil）
+	BeforeAllowTrafficHook awslambda.IFunction
+	AfterAllowTrafficHook  awslambda.IFunction
+}
+
+func NewBlueGreenDeployment(scope constructs.Construct, id string, props *BlueGreenDeploymentProps) *BlueGreenDeployment {
+	this := constructs.NewConstruct(scope, &id)
+	resourceName := props.ResourceName
+
+	// CodeDeploy設定
+	codeDeployApp := awscodedeploy.NewEcsApplication(this, jsii.String("Application"), &awscodedeploy.EcsApplicationProps{
+		ApplicationName: jsii.String(resourceName + "-deployment"),
+	})
+
+	codeDeployRole := awsiam.NewRole(this, jsii.String("ServiceRole"), &awsiam.RoleProps{
+		RoleName:  jsii.String(resourceName + "-deploy-role"),
+		AssumedBy: awsiam.NewServicePrincipal(jsii.String("codedeploy.amazonaws.com"), nil),
+	})
+	grantBlueGreenDeployment(codeDeployRole, props)
+
+	// CodeDeploy はデプロイのたびに blue と green のターゲットグループを入れ替えるため、両方を監視する
+	var alarms []awscloudwatch.Alarm
+	var autoRollback *awscodedeploy.AutoRollbackConfig
+	if props.Alarms != nil {
+		for _, tg := range []struct {
+			id          string
+			name        string
+			targetGroup awselasticloadbalancingv2.IApplicationTargetGroup
+		}{
+			{"BlueTargetGroupAlarms", resourceName + "-tg1", props.BlueTargetGroup},
+			{"GreenTargetGroupAlarms", resourceName + "-tg2", props.GreenTargetGroup},
+		} {
+			alarms = append(alarms, monitoring.NewTargetGroupAlarms(constructs.NewConstruct(this, jsii.String(tg.id)), tg.name, tg.targetGroup, *props.Alarms)...)
+		}
+		autoRollback = &awscodedeploy.AutoRollbackConfig{
+			FailedDeployment:  jsii.Bool(true),
+			StoppedDeployment: jsii.Bool(true),
+			DeploymentInAlarm: jsii.Bool(len(alarms) > 0),
+		}
+	}
+
+	deploymentGroup := awscodedeploy.NewEcsDeploymentGroup(this, jsii.String("DeploymentGroup"), &awscodedeploy.EcsDeploymentGroupProps{
+		Application:         codeDeployApp,
+		DeploymentGroupName: jsii.String(resourceName + "-deployment-group"),
+		Service:             props.Service,
+		BlueGreenDeploymentConfig: &awscodedeploy.EcsBlueGreenDeploymentConfig{
+			BlueTargetGroup:     props.BlueTargetGroup,
+			GreenTargetGroup:    props.GreenTargetGroup,
+			Listener:            props.ProdListener,
+			TestListener:        props.TestListener,
+			TerminationWaitTime: props.TerminationWaitTime,
+		},
+		DeploymentConfig: deploymentConfig(this, resourceName, props.TrafficShifting),
+		Alarms:           alarmList(alarms),
+		AutoRollback:     autoRollback,
+		// デプロイグループは AWSCodeDeployRoleForECS をアタッチしようとするため、ポリシーを変更できないロールとして渡す
+		Role: codeDeployRole.WithoutPolicyUpdates(nil),
+	})
+
+	var beforeAllowTraffic, afterAllowTraffic awslambda.IFunction
+	if smokeTests := props.SmokeTests; smokeTests != nil {
+		if len(smokeTests.BeforeAllowTraffic) > 0 {
+			beforeAllowTraffic = newSmokeTestHook(this, "BeforeAllowTrafficHook", resourceName+"-before-allow-traffic", smokeTests.TestEndpoint, smokeTests.BeforeAllowTraffic, deploymentGroup, codeDeployRole)
+		}
+		if len(smokeTests.AfterAllowTraffic) > 0 {
+			afterAllowTraffic = newSmokeTestHook(this, "AfterAllowTrafficHook", resourceName+"-after-allow-traffic", smokeTests.TestEndpoint, smokeTests.AfterAllowTraffic, deploymentGroup, codeDeployRole)
+		}
+	}
+
+	// アラームの状態はデプロイ中に CodeDeploy が確認する
+	for _, alarm := range alarms {
+		codeDeployRole.AddToPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+			Actions:   jsii.Strings("cloudwatch:DescribeAlarms"),
+			Resources: jsii.Strings(*alarm.AlarmArn()),
+		}))
+	}
+
+	return &BlueGreenDeployment{
+		Construct:       this,
+		Application:     codeDeployApp,
+		DeploymentGroup: deploymentGroup,
+		ServiceRole:     codeDeployRole,
+		Alarms:          alarms,
+
+		BeforeAllowTrafficHook: beforeAllowTraffic,
+		AfterAllowTrafficHook:  afterAllowTraffic,
+	}
+}
+
+// AWSCodeDeployRoleForECS の代わりに、デプロイするサービスとリスナーに限った権限を付与する
+func grantBlueGreenDeployment(role awsiam.Role, props *BlueGreenDeploymentProps) {
+	stack := awscdk.Stack_Of(role)
+	taskSets := stack.FormatArn(&awscdk.ArnComponents{
+		Service:      jsii.String("ecs"),
+		Resource:     jsii.String("task-set"),
+		ResourceName: jsii.String(*props.Service.Cluster().ClusterName() + "/" + *props.Service.ServiceName() + "/*"),
+	})
+
+	role.AddToPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions: jsii.Strings(
+			"ecs:DescribeServices",
+			"ecs:CreateTaskSet",
+			"ecs:UpdateServicePrimaryTaskSet",
+			"ecs:DeleteTaskSet",
+		),
+		Resources: jsii.Strings(*props.Service.ServiceArn(), *taskSets),
+	}))
+	role.AddToPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("elasticloadbalancing:ModifyListener"),
+		Resources: jsii.Strings(*props.ProdListener.ListenerArn(), *props.TestListener.ListenerArn()),
+	}))
+	// Describe 系の API はリソースを指定できない
+	role.AddToPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions: jsii.Strings(
+			"elasticloadbalancing:DescribeTargetGroups",
+			"elasticloadbalancing:DescribeListeners",
+			"elasticloadbalancing:DescribeRules",
+			"elasticloadbalancing:DescribeTargetHealth",
+		),
+		Resources: jsii.Strings("*"),
+	}))
+	if taskDef := props.TaskDefinition; taskDef != nil {
+		role.AddToPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+			Actions:   jsii.Strings("iam:PassRole"),
+			Resources: jsii.Strings(*taskDef.TaskRole().RoleArn(), *taskDef.ExecutionRole().RoleArn()),
+			Conditions: &map[string]interface{}{
+				"StringLike": map[string]interface{}{"iam:PassedToService": "ecs-tasks.amazonaws.com"},
+			},
+		}))
+	}
+}
+
+// 定義済みの設定に無い割合・間隔の場合はデプロイ設定を作成する
+func deploymentConfig(scope constructs.Construct, resourceName string, shifting *TrafficShiftingProps) awscodedeploy.IEcsDeploymentConfig {
+	if shifting == nil {
+		return awscodedeploy.EcsDeploymentConfig_LINEAR_10PERCENT_EVERY_1MINUTES()
+	}
+
+	var routing awscodedeploy.TrafficRouting
+	switch shifting.Type {
+	case TrafficShiftingAllAtOnce:
+		return awscodedeploy.EcsDeploymentConfig_ALL_AT_ONCE()
+	case TrafficShiftingCustom:
+		return awscodedeploy.EcsDeploymentConfig_FromEcsDeploymentConfigName(scope, jsii.String("DeploymentConfig"), jsii.String(shifting.DeploymentConfigName))
+	case TrafficShiftingLinear:
+		switch {
+		case shifting.Percentage == 10 && shifting.IntervalMinutes == 1:
+			return awscodedeploy.EcsDeploymentConfig_LINEAR_10PERCENT_EVERY_1MINUTES()
+		case shifting.Percentage == 10 && shifting.IntervalMinutes == 3:
+			return awscodedeploy.EcsDeploymentConfig_LINEAR_10PERCENT_EVERY_3MINUTES()
+		}
+		routing = awscodedeploy.TrafficRouting_TimeBasedLinear(&awscodedeploy.TimeBasedLinearTrafficRoutingProps{
+			Percentage: jsii.Number(shifting.Percentage),
+			Interval:   awscdk.Duration_Minutes(jsii.Number(shifting.IntervalMinutes)),
+		})
+	case TrafficShiftingCanary:
+		switch {
+		case shifting.Percentage == 10 && shifting.IntervalMinutes == 5:
+			return awscodedeploy.EcsDeploymentConfig_CANARY_10PERCENT_5MINUTES()
+		case shifting.Percentage == 10 && shifting.IntervalMinutes == 15:
+			return awscodedeploy.EcsDeploymentConfig_CANARY_10PERCENT_15MINUTES()
+		}
+		routing = awscodedeploy.TrafficRouting_TimeBasedCanary(&awscodedeploy.TimeBasedCanaryTrafficRoutingProps{
+			Percentage: jsii.Number(shifting.Percentage),
+			Interval:   awscdk.Duration_Minutes(jsii.Number(shifting.IntervalMinutes)),
+		})
+	default:
+		return awscodedeploy.EcsDeploymentConfig_LINEAR_10PERCENT_EVERY_1MINUTES()
+	}
+
+	return awscodedeploy.NewEcsDeploymentConfig(scope, jsii.String("DeploymentConfig"), &awscodedeploy.EcsDeploymentConfigProps{
+		DeploymentConfigName: jsii.String(resourceName + "-deployment-config"),
+		TrafficRouting:       routing,
+	})
+}
+
+// アラームが無い場合はデプロイグループに含めない
+func alarmList(alarms []awscloudwatch.Alarm) *[]awscloudwatch.IAlarm {
+	if len(alarms) == 0 {
+		return nil
+	}
+	list := make([]awscloudwatch.IAlarm, 0, len(alarms))
+	for _, alarm := range alarms {
+		list = append(list, alarm)
+	}
+	return &list
+}
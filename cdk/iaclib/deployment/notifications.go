@@ -0,0 +1,79 @@
+package deployment
+
+import (
+	_ "embed"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscodepipeline"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscodestarnotifications"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslambda"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslogs"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awssecretsmanager"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awssns"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awssnssubscriptions"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+//go:embed notify_webhook.js
+var notifyWebhookSource string
+
+// NotificationProps はパイプラインの状態の変化と手動承認の通知先
+type NotificationProps struct {
+	// メールで通知する宛先
+	Emails []string
+	// Slack 互換の Incoming Webhook の URL を値に持つシークレット（nil の場合は使わない）。
+	// URL はトークンを含むため、テンプレートには載せず Lambda が起動時に読み込む
+	WebhookSecret awssecretsmanager.ISecret
+}
+
+// パイプラインの実行結果と、手動承認の依頼・結果を通知する
+var pipelineNotificationEvents = []awscodepipeline.PipelineNotificationEvents{
+	awscodepipeline.PipelineNotificationEvents_PIPELINE_EXECUTION_STARTED,
+	awscodepipeline.PipelineNotificationEvents_PIPELINE_EXECUTION_SUCCEEDED,
+	awscodepipeline.PipelineNotificationEvents_PIPELINE_EXECUTION_FAILED,
+	awscodepipeline.PipelineNotificationEvents_PIPELINE_EXECUTION_CANCELED,
+	awscodepipeline.PipelineNotificationEvents_MANUAL_APPROVAL_NEEDED,
+	awscodepipeline.PipelineNotificationEvents_MANUAL_APPROVAL_SUCCEEDED,
+	awscodepipeline.PipelineNotificationEvents_MANUAL_APPROVAL_FAILED,
+}
+
+// CodeStar Notifications で SNS トピックに通知し、トピックからメールと Webhook に配信する
+func newPipelineNotifications(scope constructs.Construct, resourceName string, pipeline awscodepipeline.Pipeline, props *NotificationProps) awssns.Topic {
+	topic := awssns.NewTopic(scope, jsii.String("NotificationTopic"), &awssns.TopicProps{
+		TopicName: jsii.String(resourceName + "-pipeline-notifications"),
+	})
+
+	for _, email := range props.Emails {
+		topic.AddSubscription(awssnssubscriptions.NewEmailSubscription(jsii.String(email), nil))
+	}
+
+	if props.WebhookSecret != nil {
+		functionName := resourceName + "-pipeline-webhook"
+		webhook := awslambda.NewFunction(scope, jsii.String("NotificationWebhook"), &awslambda.FunctionProps{
+			FunctionName: jsii.String(functionName),
+			Runtime:      awslambda.Runtime_NODEJS_20_X(),
+			Handler:      jsii.String("index.handler"),
+			Code:         awslambda.Code_FromInline(jsii.String(notifyWebhookSource)),
+			Timeout:      awscdk.Duration_Seconds(jsii.Number(30)),
+			Environment: &map[string]*string{
+				"WEBHOOK_SECRET_ARN": props.WebhookSecret.SecretArn(),
+			},
+			LogGroup: awslogs.NewLogGroup(scope, jsii.String("NotificationWebhookLogGroup"), &awslogs.LogGroupProps{
+				LogGroupName:  jsii.String("/aws/lambda/" + functionName),
+				RemovalPolicy: awscdk.RemovalPolicy_DESTROY,
+				Retention:     awslogs.RetentionDays_ONE_MONTH,
+			}),
+		})
+		props.WebhookSecret.GrantRead(webhook, nil)
+		topic.AddSubscription(awssnssubscriptions.NewLambdaSubscription(webhook, nil))
+	}
+
+	pipeline.NotifyOn(jsii.String("NotificationRule"), topic, &awscodepipeline.PipelineNotifyOnOptions{
+		NotificationRuleName: jsii.String(resourceName + "-pipeline"),
+		DetailType:           awscodestarnotifications.DetailType_FULL,
+		Events:               &pipelineNotificationEvents,
+	})
+
+	return topic
+}
@@ -0,0 +1,44 @@
+// SNS に届いたパイプラインの通知を、Slack 互換の Incoming Webhook（{"text": ...}）に転送する
+const { SecretsManagerClient, GetSecretValueCommand } = require("@aws-sdk/client-secrets-manager");
+
+const secretsManager = new SecretsManagerClient({});
+const secretArn = process.env.WEBHOOK_SECRET_ARN;
+
+// Webhook の URL はトークンを含むため、環境変数ではなくシークレットから読み込み、コンテナの間は使い回す
+let webhookUrl;
+
+async function getWebhookUrl() {
+  if (!webhookUrl) {
+    const res = await secretsManager.send(new GetSecretValueCommand({ SecretId: secretArn }));
+    webhookUrl = res.SecretString.trim();
+  }
+  return webhookUrl;
+}
+
+function format(message) {
+  let event;
+  try {
+    event = JSON.parse(message);
+  } catch {
+    return message;
+  }
+  const detail = event.detail || {};
+  const target = detail.stage ? ` ${detail.stage}/${detail.action}` : "";
+  const consoleUrl = `https://${event.region}.console.aws.amazon.com/codesuite/codepipeline/pipelines/${detail.pipeline}/view`;
+  return `[${detail.pipeline}]${target} ${detail.state} (${event.detailType})\n${consoleUrl}`;
+}
+
+exports.handler = async (event) => {
+  const url = await getWebhookUrl();
+  for (const record of event.Records) {
+    const res = await fetch(url, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ text: format(record.Sns.Message) }),
+      signal: AbortSignal.timeout(10000),
+    });
+    if (!res.ok) {
+      throw new Error(`webhook responded with ${res.status}: ${await res.text()}`);
+    }
+  }
+};
@@ -0,0 +1,258 @@
+package deployment
+
+import (
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscodebuild"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscodepipeline"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscodepipelineactions"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsecr"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslogs"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awss3"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awssns"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+type BlueGreenPipelineProps struct {
+	BlueGreenDeploymentProps
+	// GitHub との CodeStar Connections 接続
+	GitHubConnectionArn   string
+	GitHubRepositoryOwner string
+	GitHubRepositoryName  string
+	GitHubBranchName      string
+	// ビルドしたイメージを push する ECR リポジトリ
+	Repository awsecr.IRepository
+	// 指定した場合、プルリクエストをビルドしてテストするプロジェクトを作成する（デプロイはしない）
+	PullRequestValidation *PullRequestValidationProps
+	// 指定した場合、パイプラインの状態の変化と手動承認を通知する
+	Notifications *NotificationProps
+}
+
+type PullRequestValidationProps struct {
+	// イメージのビルド後に実行するコマンド。ビルドしたイメージは $IMAGE で参照できる（例: docker run --rm $IMAGE bin/rails test）
+	TestCommands []string
+}
+
+// BlueGreenPipeline は Source → Build → Approval → Deploy のパイプラインで BlueGreenDeployment にデプロイする
+type BlueGreenPipeline struct {
+	constructs.Construct
+	Deployment   *BlueGreenDeployment
+	BuildProject awscodebuild.PipelineProject
+	Pipeline     awscodepipeline.Pipeline
+	// プルリクエストの検証用プロジェクト（作成しない場合は nil）
+	PullRequestProject awscodebuild.Project
+	// パイプラインの通知先のトピック（作成しない場合は nil）
+	NotificationTopic awssns.ITopic
+}
+
+func NewBlueGreenPipeline(scope constructs.Construct, id string, props *BlueGreenPipelineProps) *BlueGreenPipeline {
+	this := constructs.NewConstruct(scope, &id)
+	resourceName := props.ResourceName
+	repositoryOwner := props.GitHubRepositoryOwner
+	repositoryName := props.GitHubRepositoryName
+	branchName := props.GitHubBranchName
+
+	deployment := NewBlueGreenDeployment(this, "Deployment", &props.BlueGreenDeploymentProps)
+
+	// CodeBuild設定
+	// 権限は push 先のリポジトリ、ログループ、<ResourceName>/ 以下の SSM パラメータに限る
+	// （アーティファクトバケットの権限はパイプラインのアクションが付与する）
+	codeBuildRole := awsiam.NewRole(this, jsii.String("BuildRole"), &awsiam.RoleProps{
+		RoleName:  jsii.String(resourceName + "-codebuild-role"),
+		AssumedBy: awsiam.NewServicePrincipal(jsii.String("codebuild.amazonaws.com"), nil),
+	})
+	props.Repository.GrantPullPush(codeBuildRole)
+	codeBuildRole.AddToPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions: jsii.Strings("ssm:GetParameters"),
+		Resources: jsii.Strings(*awscdk.Stack_Of(this).FormatArn(&awscdk.ArnComponents{
+			Service:      jsii.String("ssm"),
+			Resource:     jsii.String("parameter"),
+			ResourceName: jsii.String(resourceName + "/*"),
+		})),
+		Effect: awsiam.Effect_ALLOW,
+	}))
+
+	// ソースはパイプラインの Source ステージの成果物を使う
+	codeBuildProject := awscodebuild.NewPipelineProject(this, jsii.String("BuildProject"), &awscodebuild.PipelineProjectProps{
+		ProjectName: jsii.String(resourceName + "-codebuild-project"),
+		Environment: &awscodebuild.BuildEnvironment{
+			BuildImage:  awscodebuild.LinuxBuildImage_AMAZON_LINUX_2023_5(),
+			ComputeType: awscodebuild.ComputeType_SMALL,
+			Privileged:  jsii.Bool(true),
+		},
+		// buildspec・appspec・taskdef はアプリのリポジトリに置かず、インフラの定義から作成する
+		BuildSpec: buildSpec(),
+		EnvironmentVariables: &map[string]*awscodebuild.BuildEnvironmentVariable{
+			"REPOSITORY_URI": {Value: props.Repository.RepositoryUri()},
+			"APPSPEC":        {Value: appSpecTemplate(props.TaskDefinition, deployment)},
+			"TASKDEF":        {Value: taskDefinitionTemplate(props.TaskDefinition)},
+		},
+		Logging: buildLogging(this, "BuildLogGroup", resourceName+"-codebuild-project"),
+		Role:    codeBuildRole,
+	})
+
+	// CodePipeline設定
+	artifactBucket := awss3.NewBucket(this, jsii.String("ArtifactBucket"), &awss3.BucketProps{
+		BucketName: jsii.String(resourceName + "-codepipeline-artifacts"),
+		// RemovalPolicy:     awscdk.RemovalPolicy_DESTROY,
+		// AutoDeleteObjects: jsii.Bool(true),
+	})
+
+	codePipelineRole := awsiam.NewRole(this, jsii.String("PipelineRole"), &awsiam.RoleProps{
+		RoleName:  jsii.String(resourceName + "-codepipeline-role"),
+		AssumedBy: awsiam.NewServicePrincipal(jsii.String("codepipeline.amazonaws.com"), nil),
+	})
+
+	codePipeline := awscodepipeline.NewPipeline(this, jsii.String("Pipeline"), &awscodepipeline.PipelineProps{
+		PipelineName:   jsii.String(resourceName + "-codepipeline"),
+		ArtifactBucket: artifactBucket,
+		Role:           codePipelineRole,
+	})
+
+	// Source
+	sourceOutput := awscodepipeline.NewArtifact(jsii.String("SourceOutput"), nil)
+
+	sourceAction := awscodepipelineactions.NewCodeStarConnectionsSourceAction(&awscodepipelineactions.CodeStarConnectionsSourceActionProps{
+		ActionName:    jsii.String("SourceAction"),
+		Owner:         jsii.String(repositoryOwner),
+		Repo:          jsii.String(repositoryName),
+		Branch:        jsii.String(branchName),
+		ConnectionArn: jsii.String(props.GitHubConnectionArn),
+		Output:        sourceOutput,
+		Role:          codePipelineRole,
+	})
+
+	codePipeline.AddStage(&awscodepipeline.StageOptions{
+		StageName: jsii.String("Source"),
+		Actions:   &[]awscodepipeline.IAction{sourceAction},
+	})
+
+	// Build
+	buildOutput := awscodepipeline.NewArtifact(jsii.String("BuildOutput"), nil)
+
+	buildAction := awscodepipelineactions.NewCodeBuildAction(&awscodepipelineactions.CodeBuildActionProps{
+		ActionName: jsii.String("Build"),
+		Project:    codeBuildProject,
+		Input:      sourceOutput,
+		Outputs:    &[]awscodepipeline.Artifact{buildOutput},
+		Role:       codePipelineRole,
+	})
+
+	codePipeline.AddStage(&awscodepipeline.StageOptions{
+		StageName: jsii.String("Build"),
+		Actions:   &[]awscodepipeline.IAction{buildAction},
+	})
+
+	// Approval
+	approvalAction := awscodepipelineactions.NewManualApprovalAction(&awscodepipelineactions.ManualApprovalActionProps{
+		ActionName: jsii.String("Approval"),
+		Role:       codePipelineRole,
+	})
+
+	codePipeline.AddStage(&awscodepipeline.StageOptions{
+		StageName: jsii.String("Approval"),
+		Actions:   &[]awscodepipeline.IAction{approvalAction},
+	})
+
+	// Deploy
+	deployAction := awscodepipelineactions.NewCodeDeployEcsDeployAction(&awscodepipelineactions.CodeDeployEcsDeployActionProps{
+		ActionName:                 jsii.String("Deploy"),
+		DeploymentGroup:            deployment.DeploymentGroup,
+		AppSpecTemplateFile:        buildOutput.AtPath(jsii.String(appSpecFile)),
+		TaskDefinitionTemplateFile: buildOutput.AtPath(jsii.String(taskDefFile)),
+		// imageDetail.json の ImageURI を taskdef のプレースホルダーに入れる
+		ContainerImageInputs: &[]*awscodepipelineactions.CodeDeployEcsContainerImageInput{
+			{
+				Input:                     buildOutput,
+				TaskDefinitionPlaceholder: jsii.String(imagePlaceholder),
+			},
+		},
+		Role: codePipelineRole,
+	})
+
+	codePipeline.AddStage(&awscodepipeline.StageOptions{
+		StageName: jsii.String("Deploy"),
+		Actions:   &[]awscodepipeline.IAction{deployAction},
+	})
+
+	var pullRequestProject awscodebuild.Project
+	if props.PullRequestValidation != nil {
+		pullRequestProject = newPullRequestProject(this, props)
+	}
+
+	var notificationTopic awssns.ITopic
+	if props.Notifications != nil {
+		notificationTopic = newPipelineNotifications(this, resourceName, codePipeline, props.Notifications)
+	}
+
+	return &BlueGreenPipeline{
+		Construct:    this,
+		Deployment:   deployment,
+		BuildProject: codeBuildProject,
+		Pipeline:     codePipeline,
+
+		PullRequestProject: pullRequestProject,
+		NotificationTopic:  notificationTopic,
+	}
+}
+
+// デプロイ対象のブランチへのプルリクエストが作成・更新されたらビルドとテストを実行する
+func newPullRequestProject(scope constructs.Construct, props *BlueGreenPipelineProps) awscodebuild.Project {
+	source := awscodebuild.Source_GitHub(&awscodebuild.GitHubSourceProps{
+		Owner:             jsii.String(props.GitHubRepositoryOwner),
+		Repo:              jsii.String(props.GitHubRepositoryName),
+		ReportBuildStatus: jsii.Bool(true),
+		Webhook:           jsii.Bool(true),
+		WebhookFilters: &[]awscodebuild.FilterGroup{
+			awscodebuild.FilterGroup_InEventOf(
+				awscodebuild.EventAction_PULL_REQUEST_CREATED,
+				awscodebuild.EventAction_PULL_REQUEST_UPDATED,
+				awscodebuild.EventAction_PULL_REQUEST_REOPENED,
+			).AndBaseBranchIs(jsii.String(props.GitHubBranchName)),
+		},
+	})
+
+	project := awscodebuild.NewProject(scope, jsii.String("PullRequestProject"), &awscodebuild.ProjectProps{
+		ProjectName: jsii.String(props.ResourceName + "-pull-request"),
+		Source:      source,
+		Environment: &awscodebuild.BuildEnvironment{
+			BuildImage:  awscodebuild.LinuxBuildImage_AMAZON_LINUX_2023_5(),
+			ComputeType: awscodebuild.ComputeType_SMALL,
+			Privileged:  jsii.Bool(true),
+		},
+		BuildSpec: pullRequestBuildSpec(props.PullRequestValidation.TestCommands),
+		Logging:   buildLogging(scope, "PullRequestLogGroup", props.ResourceName+"-pull-request"),
+	})
+
+	// GitHub への接続はパイプラインと同じ CodeStar Connections の接続を使い、別途 OAuth トークンを登録しない
+	cfnProject := project.Node().DefaultChild().(awscodebuild.CfnProject)
+	cfnProject.AddPropertyOverride(jsii.String("Source.Auth"), map[string]interface{}{
+		"Type":     "CODECONNECTIONS",
+		"Resource": props.GitHubConnectionArn,
+	})
+	project.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions: jsii.Strings(
+			"codeconnections:GetConnectionToken",
+			"codeconnections:UseConnection",
+			"codestar-connections:GetConnectionToken",
+			"codestar-connections:UseConnection",
+		),
+		Resources: jsii.Strings(props.GitHubConnectionArn),
+	}))
+
+	return project
+}
+
+// ビルドのログは保持期間を決めたロググループに出力し、ロールの権限もこのロググループに限る
+func buildLogging(scope constructs.Construct, id string, projectName string) *awscodebuild.LoggingOptions {
+	return &awscodebuild.LoggingOptions{
+		CloudWatch: &awscodebuild.CloudWatchLoggingOptions{
+			LogGroup: awslogs.NewLogGroup(scope, jsii.String(id), &awslogs.LogGroupProps{
+				LogGroupName:  jsii.String("/aws/codebuild/" + projectName),
+				RemovalPolicy: awscdk.RemovalPolicy_DESTROY,
+				Retention:     awslogs.RetentionDays_ONE_MONTH,
+			}),
+		},
+	}
+}
@@ -0,0 +1,62 @@
+// CodeDeploy のライフサイクルフックとして、テストリスナーに HTTP のスモークテストを実行する
+const {
+  CodeDeployClient,
+  PutLifecycleEventHookExecutionStatusCommand,
+} = require("@aws-sdk/client-codedeploy");
+
+const codedeploy = new CodeDeployClient({});
+const baseUrl = process.env.BASE_URL;
+const checks = JSON.parse(process.env.SMOKE_CHECKS);
+
+// 新しいタスクがターゲットグループに登録された直後は失敗することがあるため、数回試す
+const attempts = 3;
+const retryDelayMs = 5000;
+const timeoutMs = 10000;
+
+async function run(check) {
+  const url = baseUrl + check.path;
+  let lastError;
+  for (let attempt = 1; attempt <= attempts; attempt++) {
+    try {
+      const res = await fetch(url, { redirect: "manual", signal: AbortSignal.timeout(timeoutMs) });
+      const body = await res.text();
+      if (res.status !== check.expectedStatus) {
+        throw new Error(`${url}: expected status ${check.expectedStatus}, got ${res.status}`);
+      }
+      if (check.bodyContains && !body.includes(check.bodyContains)) {
+        throw new Error(`${url}: response body does not contain ${JSON.stringify(check.bodyContains)}`);
+      }
+      console.log(`ok: ${url}`);
+      return;
+    } catch (err) {
+      lastError = err;
+      console.log(`attempt ${attempt}/${attempts} failed: ${err.message}`);
+      if (attempt < attempts) {
+        await new Promise((resolve) => setTimeout(resolve, retryDelayMs));
+      }
+    }
+  }
+  throw lastError;
+}
+
+exports.handler = async (event) => {
+  let status = "Succeeded";
+  try {
+    for (const check of checks) {
+      await run(check);
+    }
+  } catch (err) {
+    console.error(err);
+    status = "Failed";
+  }
+
+  // 結果を返さないとフックがタイムアウトするまでデプロイが止まる
+  await codedeploy.send(
+    new PutLifecycleEventHookExecutionStatusCommand({
+      deploymentId: event.DeploymentId,
+      lifecycleEventHookExecutionId: event.LifecycleEventHookExecutionId,
+      status,
+    }),
+  );
+  return status;
+};
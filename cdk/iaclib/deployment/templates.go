@@ -0,0 +1,142 @@
+package deployment
+
+import (
+	"strconv"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscodebuild"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsecs"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslambda"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// taskdef.json のイメージのプレースホルダー。CodeDeploy のデプロイアクションがビルドしたイメージの URI に置き換える
+const imagePlaceholder = "IMAGE1_NAME"
+
+// ビルド結果として出力するファイル
+const (
+	imageDetailFile = "imageDetail.json"
+	appSpecFile     = "appspec.yaml"
+	taskDefFile     = "taskdef.json"
+)
+
+// イメージをビルドして ECR に push し、デプロイに使う appspec と taskdef を出力する
+func buildSpec() awscodebuild.BuildSpec {
+	return awscodebuild.BuildSpec_FromObject(&map[string]interface{}{
+		"version": "0.2",
+		"phases": map[string]interface{}{
+			"pre_build": map[string]interface{}{
+				"commands": []string{
+					"aws ecr get-login-password --region $AWS_DEFAULT_REGION | docker login --username AWS --password-stdin ${REPOSITORY_URI%%/*}",
+					"IMAGE_TAG=$(echo $CODEBUILD_RESOLVED_SOURCE_VERSION | cut -c 1-7)",
+				},
+			},
+			"build": map[string]interface{}{
+				"commands": []string{
+					"docker build -t $REPOSITORY_URI:$IMAGE_TAG .",
+					// サービスの初回作成時は latest タグのイメージを使う
+					"docker tag $REPOSITORY_URI:$IMAGE_TAG $REPOSITORY_URI:latest",
+				},
+			},
+			"post_build": map[string]interface{}{
+				"commands": []string{
+					"docker push $REPOSITORY_URI:$IMAGE_TAG",
+					"docker push $REPOSITORY_URI:latest",
+					`printf '{"ImageURI":"%s"}' $REPOSITORY_URI:$IMAGE_TAG > ` + imageDetailFile,
+					`printf '%s' "$APPSPEC" > ` + appSpecFile,
+					`printf '%s' "$TASKDEF" > ` + taskDefFile,
+				},
+			},
+		},
+		"artifacts": map[string]interface{}{
+			"files": []string{imageDetailFile, appSpecFile, taskDefFile},
+		},
+	})
+}
+
+// プルリクエストのイメージをビルドしてテストする（ECR への push はしない）
+func pullRequestBuildSpec(testCommands []string) awscodebuild.BuildSpec {
+	return awscodebuild.BuildSpec_FromObject(&map[string]interface{}{
+		"version": "0.2",
+		"phases": map[string]interface{}{
+			"pre_build": map[string]interface{}{
+				"commands": []string{
+					"IMAGE=pull-request:$(echo $CODEBUILD_RESOLVED_SOURCE_VERSION | cut -c 1-7)",
+				},
+			},
+			"build": map[string]interface{}{
+				"commands": append([]string{"docker build -t $IMAGE ."}, testCommands...),
+			},
+		},
+	})
+}
+
+// CodeDeploy の appspec（JSON は YAML としても読める）
+func appSpecTemplate(taskDef awsecs.FargateTaskDefinition, deployment *BlueGreenDeployment) *string {
+	container := taskDef.DefaultContainer()
+	appSpec := map[string]interface{}{
+		"version": "0.0",
+		"Resources": []interface{}{
+			map[string]interface{}{
+				"TargetService": map[string]interface{}{
+					"Type": "AWS::ECS::Service",
+					"Properties": map[string]interface{}{
+						// CodeDeploy のデプロイアクションが登録したタスク定義の ARN に置き換える
+						"TaskDefinition": "<TASK_DEFINITION>",
+						"LoadBalancerInfo": map[string]interface{}{
+							"ContainerName": container.ContainerName(),
+							"ContainerPort": container.ContainerPort(),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	var hooks []interface{}
+	for _, hook := range []struct {
+		event    string
+		function awslambda.IFunction
+	}{
+		{"BeforeAllowTraffic", deployment.BeforeAllowTrafficHook},
+		{"AfterAllowTraffic", deployment.AfterAllowTrafficHook},
+	} {
+		if hook.function != nil {
+			hooks = append(hooks, map[string]interface{}{hook.event: hook.function.FunctionName()})
+		}
+	}
+	if len(hooks) > 0 {
+		appSpec["Hooks"] = hooks
+	}
+
+	return awscdk.Stack_Of(taskDef).ToJsonString(appSpec, nil)
+}
+
+// サービスのタスク定義と同じ内容の taskdef（イメージのみプレースホルダーにする）
+func taskDefinitionTemplate(taskDef awsecs.FargateTaskDefinition) *string {
+	defaultContainer := taskDef.DefaultContainer()
+
+	var containers []interface{}
+	for _, container := range *taskDef.Containers() {
+		definition := container.RenderContainerDefinition(taskDef)
+		if *container.ContainerName() == *defaultContainer.ContainerName() {
+			definition.Image = jsii.String("<" + imagePlaceholder + ">")
+		}
+		containers = append(containers, definition)
+	}
+
+	taskDefinition := map[string]interface{}{
+		"family":                  taskDef.Family(),
+		"cpu":                     strconv.Itoa(int(*taskDef.Cpu())),
+		"memory":                  strconv.Itoa(int(*taskDef.MemoryMiB())),
+		"networkMode":             "awsvpc",
+		"requiresCompatibilities": []string{"FARGATE"},
+		"taskRoleArn":             taskDef.TaskRole().RoleArn(),
+		"containerDefinitions":    containers,
+	}
+	if executionRole := taskDef.ExecutionRole(); executionRole != nil {
+		taskDefinition["executionRoleArn"] = executionRole.RoleArn()
+	}
+
+	return awscdk.Stack_Of(taskDef).ToJsonString(taskDefinition, nil)
+}
@@ -0,0 +1,146 @@
+// Package envconfig は .env ファイルと環境変数から設定値を読み込み、各アプリで共通の項目を検証する
+package envconfig
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+// Layer は設定値の探索元の 1 つ
+type Layer func(key string) (string, bool)
+
+// Env はプロセスの環境変数 <prefix><KEY> を探す
+func Env(prefix string) Layer {
+	return func(key string) (string, bool) {
+		return os.LookupEnv(prefix + key)
+	}
+}
+
+// File は .env 形式のファイルを探す。ファイルが無い場合は空として扱い、書式の誤りはエラーにする
+func File(name string) (Layer, error) {
+	values, err := godotenv.Read(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		values = map[string]string{}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return func(key string) (string, bool) {
+		v, ok := values[key]
+		return v, ok
+	}, nil
+}
+
+// Source は設定値の探索元と、値の変換中に見つかったエラーを保持する
+type Source struct {
+	layers []Layer
+	errs   []error
+}
+
+// NewSource は layers を先頭から順に探す Source を返す
+func NewSource(layers ...Layer) *Source {
+	return &Source{layers: layers}
+}
+
+// Errors は数値や真偽値に変換できなかった設定値のエラーを返す
+func (s *Source) Errors() []error {
+	return s.errs
+}
+
+func (s *Source) lookup(key string) (string, bool) {
+	for _, layer := range s.layers {
+		if v, ok := layer(key); ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// Get は値を返す（未設定の場合は空）
+func (s *Source) Get(key string) string {
+	v, _ := s.lookup(key)
+	return v
+}
+
+// String は値を返す（未設定または空の場合は fallback）
+func (s *Source) String(key, fallback string) string {
+	if v, ok := s.lookup(key); ok && v != "" {
+		return v
+	}
+	return fallback
+}
+
+// Optional は未設定の場合は fallback を返し、空文字が設定されている場合は無効として空を返す
+func (s *Source) Optional(key, fallback string) string {
+	if v, ok := s.lookup(key); ok {
+		return strings.TrimSpace(v)
+	}
+	return fallback
+}
+
+// List はカンマ区切りの値をリストとして返す
+func (s *Source) List(key string, fallback []string) []string {
+	v, ok := s.lookup(key)
+	if !ok || strings.TrimSpace(v) == "" {
+		return fallback
+	}
+	return SplitList(v)
+}
+
+// Int は整数として返す（未設定または空の場合は fallback）
+func (s *Source) Int(key string, fallback int) int {
+	v, ok := s.lookup(key)
+	if !ok || v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		s.errs = append(s.errs, fmt.Errorf("%s must be a number, got %q", key, v))
+		return fallback
+	}
+	return n
+}
+
+// Float は数値として返す（未設定または空の場合は fallback）
+func (s *Source) Float(key string, fallback float64) float64 {
+	v, ok := s.lookup(key)
+	if !ok || v == "" {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		s.errs = append(s.errs, fmt.Errorf("%s must be a number, got %q", key, v))
+		return fallback
+	}
+	return f
+}
+
+// Bool は真偽値として返す（未設定または空の場合は fallback）
+func (s *Source) Bool(key string, fallback bool) bool {
+	v, ok := s.lookup(key)
+	if !ok || v == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		s.errs = append(s.errs, fmt.Errorf("%s must be true or false, got %q", key, v))
+		return fallback
+	}
+	return b
+}
+
+// SplitList はカンマ区切りの値をリストとして返す
+func SplitList(v string) []string {
+	var values []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
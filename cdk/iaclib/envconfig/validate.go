@@ -0,0 +1,104 @@
+package envconfig
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"iaclib/network"
+)
+
+var (
+	accountIDPattern    = regexp.MustCompile(`^\d{12}$`)
+	regionPattern       = regexp.MustCompile(`^[a-z]{2}(-gov|-iso[a-z]?)?-[a-z]+-\d+$`)
+	resourceNamePattern = regexp.MustCompile(`^[a-z][a-z0-9-]*[a-z0-9]$`)
+	emailPattern        = regexp.MustCompile(`^[^@\s,]+@[^@\s,]+\.[^@\s,]+$`)
+)
+
+// ALB やターゲットグループ名は 32 文字までなので、"-alb" や "-tg1" を付けても収まる長さに制限する
+const MaxResourceNameLength = 28
+
+// Field は設定のキーと値
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Required は値が空のキーを報告する
+func Required(fields ...Field) []error {
+	var errs []error
+	for _, f := range fields {
+		if f.Value == "" {
+			errs = append(errs, fmt.Errorf("%s is required", f.Name))
+		}
+	}
+	return errs
+}
+
+// CheckAccountID は ACCOUNT_ID が 12 桁の数字か検査する（空の場合は Required で報告する）
+func CheckAccountID(v string) []error {
+	if v != "" && !accountIDPattern.MatchString(v) {
+		return []error{fmt.Errorf("ACCOUNT_ID must be a 12-digit number, got %q", v)}
+	}
+	return nil
+}
+
+// CheckRegion は REGION がリージョン名の形式か検査する
+func CheckRegion(v string) []error {
+	if v != "" && !regionPattern.MatchString(v) {
+		return []error{fmt.Errorf("REGION must look like ap-northeast-1, got %q", v)}
+	}
+	return nil
+}
+
+// CheckResourceName は RESOURCE_NAME（ステージ名などの接尾辞を付けた後の値）の文字種と長さを検査する
+func CheckResourceName(v string) []error {
+	if v == "" {
+		return nil
+	}
+	var errs []error
+	if !resourceNamePattern.MatchString(v) {
+		errs = append(errs, fmt.Errorf("RESOURCE_NAME must contain only lower-case letters, digits and hyphens, got %q", v))
+	}
+	if len(v) > MaxResourceNameLength {
+		errs = append(errs, fmt.Errorf("RESOURCE_NAME (including any stage suffix) must be at most %d characters, got %q", MaxResourceNameLength, v))
+	}
+	return errs
+}
+
+// CheckInterfaceEndpoints は VPC_INTERFACE_ENDPOINTS が既知のサービスだけを含み、required をすべて含むか検査する
+func CheckInterfaceEndpoints(names, required []string) []error {
+	var errs []error
+	known := network.InterfaceEndpointNames()
+	for _, name := range names {
+		if !slices.Contains(known, name) {
+			errs = append(errs, fmt.Errorf("VPC_INTERFACE_ENDPOINTS contains unknown service %q (expected one of %v)", name, known))
+		}
+	}
+	for _, name := range required {
+		if !slices.Contains(names, name) {
+			errs = append(errs, fmt.Errorf("VPC_INTERFACE_ENDPOINTS must include %q", name))
+		}
+	}
+	return errs
+}
+
+// CheckEmails は key に指定したメールアドレスの形式を検査する
+func CheckEmails(key string, emails []string) []error {
+	var errs []error
+	for _, email := range emails {
+		if !emailPattern.MatchString(email) {
+			errs = append(errs, fmt.Errorf("%s contains an invalid address %q", key, email))
+		}
+	}
+	return errs
+}
+
+// ValidationError は見つかった問題をまとめて 1 つのエラーにする（問題が無い場合は nil）
+func ValidationError(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+}
@@ -0,0 +1,89 @@
+package monitoring
+
+import (
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscloudwatch"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awselasticloadbalancingv2"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// TargetGroupThresholds はターゲットグループのアラームのしきい値。0 の項目はアラームを作らない
+type TargetGroupThresholds struct {
+	// ターゲットの 5xx 応答の割合（%）
+	Max5xxRatePercent int
+	// ターゲットの応答時間の p95（ミリ秒）
+	MaxResponseTimeMillis int
+	// 異常なターゲットの数
+	MaxUnhealthyHosts int
+}
+
+// NewTargetGroupAlarms はターゲットグループの 5xx の割合、応答時間、異常なターゲット数のアラームを作成する。
+// アラーム名は <alarmNamePrefix>-target-5xx-rate のようになる
+func NewTargetGroupAlarms(scope constructs.Construct, alarmNamePrefix string, targetGroup awselasticloadbalancingv2.IApplicationTargetGroup, thresholds TargetGroupThresholds) []awscloudwatch.Alarm {
+	var alarms []awscloudwatch.Alarm
+	metrics := targetGroup.Metrics()
+	period := awscdk.Duration_Minutes(jsii.Number(1))
+
+	if thresholds.Max5xxRatePercent > 0 {
+		errorRate := awscloudwatch.NewMathExpression(&awscloudwatch.MathExpressionProps{
+			Expression: jsii.String("IF(requests > 0, 100 * errors / requests, 0)"),
+			UsingMetrics: &map[string]awscloudwatch.IMetric{
+				"errors": metrics.HttpCodeTarget(awselasticloadbalancingv2.HttpCodeTarget_TARGET_5XX_COUNT, &awscloudwatch.MetricOptions{
+					Period:    period,
+					Statistic: jsii.String("Sum"),
+				}),
+				"requests": metrics.RequestCount(&awscloudwatch.MetricOptions{
+					Period:    period,
+					Statistic: jsii.String("Sum"),
+				}),
+			},
+			Label:  jsii.String("5xx rate (%)"),
+			Period: period,
+		})
+		alarms = append(alarms, errorRate.CreateAlarm(scope, jsii.String("Target5xxRateAlarm"), &awscloudwatch.CreateAlarmOptions{
+			AlarmName:          jsii.String(alarmNamePrefix + "-target-5xx-rate"),
+			AlarmDescription:   jsii.String("5xx responses from the targets exceed the threshold"),
+			Threshold:          jsii.Number(thresholds.Max5xxRatePercent),
+			ComparisonOperator: awscloudwatch.ComparisonOperator_GREATER_THAN_THRESHOLD,
+			EvaluationPeriods:  jsii.Number(3),
+			DatapointsToAlarm:  jsii.Number(2),
+			TreatMissingData:   awscloudwatch.TreatMissingData_NOT_BREACHING,
+		}))
+	}
+
+	if thresholds.MaxResponseTimeMillis > 0 {
+		// TargetResponseTime の単位は秒
+		responseTime := metrics.TargetResponseTime(&awscloudwatch.MetricOptions{
+			Period:    period,
+			Statistic: jsii.String("p95"),
+		})
+		alarms = append(alarms, responseTime.CreateAlarm(scope, jsii.String("TargetResponseTimeAlarm"), &awscloudwatch.CreateAlarmOptions{
+			AlarmName:          jsii.String(alarmNamePrefix + "-target-response-time"),
+			AlarmDescription:   jsii.String("p95 response time of the targets exceeds the threshold"),
+			Threshold:          jsii.Number(float64(thresholds.MaxResponseTimeMillis) / 1000),
+			ComparisonOperator: awscloudwatch.ComparisonOperator_GREATER_THAN_THRESHOLD,
+			EvaluationPeriods:  jsii.Number(3),
+			DatapointsToAlarm:  jsii.Number(2),
+			TreatMissingData:   awscloudwatch.TreatMissingData_NOT_BREACHING,
+		}))
+	}
+
+	if thresholds.MaxUnhealthyHosts > 0 {
+		unhealthyHosts := metrics.UnhealthyHostCount(&awscloudwatch.MetricOptions{
+			Period:    period,
+			Statistic: jsii.String("Maximum"),
+		})
+		alarms = append(alarms, unhealthyHosts.CreateAlarm(scope, jsii.String("UnhealthyHostsAlarm"), &awscloudwatch.CreateAlarmOptions{
+			AlarmName:          jsii.String(alarmNamePrefix + "-unhealthy-hosts"),
+			AlarmDescription:   jsii.String("Unhealthy targets exceed the threshold"),
+			Threshold:          jsii.Number(thresholds.MaxUnhealthyHosts),
+			ComparisonOperator: awscloudwatch.ComparisonOperator_GREATER_THAN_OR_EQUAL_TO_THRESHOLD,
+			EvaluationPeriods:  jsii.Number(2),
+			DatapointsToAlarm:  jsii.Number(2),
+			TreatMissingData:   awscloudwatch.TreatMissingData_NOT_BREACHING,
+		}))
+	}
+
+	return alarms
+}
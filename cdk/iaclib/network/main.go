@@ -0,0 +1,294 @@
+package network
+
+import (
+	"fmt"
+	"sort"
+
+	"iaclib/security"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscertificatemanager"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awselasticloadbalancingv2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsroute53"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsroute53targets"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// VPC インターフェイスエンドポイントとして指定できるサービス
+var interfaceEndpointServices = map[string]func() awsec2.InterfaceVpcEndpointAwsService{
+	"ecr.api":        awsec2.InterfaceVpcEndpointAwsService_ECR,
+	"ecr.dkr":        awsec2.InterfaceVpcEndpointAwsService_ECR_DOCKER,
+	"logs":           awsec2.InterfaceVpcEndpointAwsService_CLOUDWATCH_LOGS,
+	"monitoring":     awsec2.InterfaceVpcEndpointAwsService_CLOUDWATCH_MONITORING,
+	"secretsmanager": awsec2.InterfaceVpcEndpointAwsService_SECRETS_MANAGER,
+	"ssm":            awsec2.InterfaceVpcEndpointAwsService_SSM,
+	"ssmmessages":    awsec2.InterfaceVpcEndpointAwsService_SSM_MESSAGES,
+	"sts":            awsec2.InterfaceVpcEndpointAwsService_STS,
+	"kms":            awsec2.InterfaceVpcEndpointAwsService_KMS,
+	"ecs":            awsec2.InterfaceVpcEndpointAwsService_ECS,
+	"ecs-agent":      awsec2.InterfaceVpcEndpointAwsService_ECS_AGENT,
+	"ecs-telemetry":  awsec2.InterfaceVpcEndpointAwsService_ECS_TELEMETRY,
+	"xray":           awsec2.InterfaceVpcEndpointAwsService_XRAY,
+}
+
+// InterfaceEndpointNames は AppNetworkProps.InterfaceEndpoints に指定できるサービス名を返す
+func InterfaceEndpointNames() []string {
+	names := make([]string, 0, len(interfaceEndpointServices))
+	for name := range interfaceEndpointServices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type AppNetworkProps struct {
+	// 各リソースの名前の接頭辞
+	ResourceName string
+	// 作成するインターフェイスエンドポイント（例: ecr.api, logs）
+	InterfaceEndpoints []string
+	// DB 用の isolated サブネットを作成するか
+	IsolatedSubnets bool
+	// ターゲットのコンテナのポートとヘルスチェックのパス
+	ContainerPort   int
+	HealthCheckPath string
+	// 指定した場合は HTTPS で公開し、HTTP は HTTPS にリダイレクトする。未指定の場合は HTTP(80) で公開する
+	Domain *DomainProps
+	// 指定した場合はブルーグリーンデプロイ用のテストリスナーと2つ目のターゲットグループを作成する
+	TestListener *TestListenerProps
+}
+
+type DomainProps struct {
+	DomainName     string
+	HostedZoneName string
+}
+
+type TestListenerProps struct {
+	Port int
+	// テストリスナーへのアクセスを許可する CIDR。未指定の場合はどこからでも許可する
+	AllowedCidrs []string
+}
+
+type AppNetwork struct {
+	constructs.Construct
+	Vpc              awsec2.IVpc
+	AlbSecurityGroup awsec2.ISecurityGroup
+	EcsSecurityGroup awsec2.ISecurityGroup
+	Alb              awselasticloadbalancingv2.ApplicationLoadBalancer
+	// 本番トラフィックを受けるリスナー
+	ProdListener awselasticloadbalancingv2.ApplicationListener
+	// HTTP を HTTPS にリダイレクトするリスナー（Domain 指定時のみ）
+	RedirectListener awselasticloadbalancingv2.ApplicationListener
+	TargetGroup1     awselasticloadbalancingv2.ApplicationTargetGroup
+	// 以下は TestListener 指定時のみ作成する
+	TestListener awselasticloadbalancingv2.ApplicationListener
+	TargetGroup2 awselasticloadbalancingv2.ApplicationTargetGroup
+}
+
+func NewAppNetwork(scope constructs.Construct, id string, props *AppNetworkProps) *AppNetwork {
+	this := constructs.NewConstruct(scope, &id)
+	resourceName := props.ResourceName
+	containerPort := props.ContainerPort
+
+	// VPCの作成
+	var subnetConfiguration *[]*awsec2.SubnetConfiguration
+	if props.IsolatedSubnets {
+		subnetConfiguration = &[]*awsec2.SubnetConfiguration{
+			{
+				Name:       jsii.String("public"),
+				SubnetType: awsec2.SubnetType_PUBLIC,
+				CidrMask:   jsii.Number(24),
+			},
+			{
+				Name:       jsii.String("private"),
+				SubnetType: awsec2.SubnetType_PRIVATE_WITH_EGRESS,
+				CidrMask:   jsii.Number(24),
+			},
+			{
+				Name:       jsii.String("isolated"),
+				SubnetType: awsec2.SubnetType_PRIVATE_ISOLATED,
+				CidrMask:   jsii.Number(24),
+			},
+		}
+	}
+
+	vpc := awsec2.NewVpc(this, jsii.String("Vpc"), &awsec2.VpcProps{
+		VpcName:                      jsii.String(resourceName + "-vpc"),
+		MaxAzs:                       jsii.Number(2),
+		NatGateways:                  jsii.Number(0),
+		RestrictDefaultSecurityGroup: jsii.Bool(false),
+		SubnetConfiguration:          subnetConfiguration,
+	})
+
+	// VPCエンドポイント（IDとサービス名はスタックのリージョンから決める）
+	region := awscdk.Stack_Of(this).Region()
+
+	vpc.AddGatewayEndpoint(jsii.String(endpointID(region, "s3")), &awsec2.GatewayVpcEndpointOptions{
+		Service: awsec2.GatewayVpcEndpointAwsService_S3(),
+	})
+
+	for _, name := range props.InterfaceEndpoints {
+		service, ok := interfaceEndpointServices[name]
+		if !ok {
+			// 未知のサービス名は synth のエラーとして報告する
+			awscdk.Annotations_Of(this).AddError(jsii.String(fmt.Sprintf("unknown interface endpoint %q (expected one of %v)", name, InterfaceEndpointNames())))
+			continue
+		}
+		vpc.AddInterfaceEndpoint(jsii.String(endpointID(region, name)), &awsec2.InterfaceVpcEndpointOptions{
+			Service: service(),
+		})
+	}
+
+	// sg for ALB
+	albSecurityGroup := awsec2.NewSecurityGroup(this, jsii.String("AlbSecurityGroup"), &awsec2.SecurityGroupProps{
+		SecurityGroupName: jsii.String(resourceName + "-sg-alb"),
+		Vpc:               vpc,
+		AllowAllOutbound:  jsii.Bool(true),
+	})
+	// インターネットからの 80 / 443 はコンプライアンスチェックで指摘しない
+	security.MarkPublicLoadBalancer(albSecurityGroup)
+	albSecurityGroup.AddIngressRule(awsec2.Peer_AnyIpv4(), awsec2.Port_Tcp(jsii.Number(80)), jsii.String("http from anywhere"), jsii.Bool(false))
+	if props.Domain != nil {
+		albSecurityGroup.AddIngressRule(awsec2.Peer_AnyIpv4(), awsec2.Port_Tcp(jsii.Number(443)), jsii.String("https from anywhere"), jsii.Bool(false))
+	}
+	if props.TestListener != nil {
+		testPort := awsec2.Port_Tcp(jsii.Number(props.TestListener.Port))
+		if len(props.TestListener.AllowedCidrs) == 0 {
+			albSecurityGroup.AddIngressRule(awsec2.Peer_AnyIpv4(), testPort, jsii.String("test listener from anywhere"), jsii.Bool(false))
+		}
+		// テストリスナーは許可した CIDR からのみ受け付ける
+		for _, cidr := range props.TestListener.AllowedCidrs {
+			albSecurityGroup.AddIngressRule(awsec2.Peer_Ipv4(jsii.String(cidr)), testPort, jsii.String("test listener from office"), jsii.Bool(false))
+		}
+	}
+
+	// sg for ECS
+	ecsSecurityGroup := awsec2.NewSecurityGroup(this, jsii.String("EcsSecurityGroup"), &awsec2.SecurityGroupProps{
+		SecurityGroupName: jsii.String(resourceName + "-sg-ecs"),
+		Vpc:               vpc,
+		AllowAllOutbound:  jsii.Bool(true),
+	})
+	ecsSecurityGroup.AddIngressRule(albSecurityGroup, awsec2.Port_Tcp(jsii.Number(containerPort)), jsii.String("http from alb"), jsii.Bool(false))
+
+	// alb
+	alb := awselasticloadbalancingv2.NewApplicationLoadBalancer(this, jsii.String("Alb"), &awselasticloadbalancingv2.ApplicationLoadBalancerProps{
+		LoadBalancerName: jsii.String(resourceName + "-alb"),
+		Vpc:              vpc,
+		InternetFacing:   jsii.Bool(true),
+		SecurityGroup:    albSecurityGroup,
+		IpAddressType:    awselasticloadbalancingv2.IpAddressType_IPV4,
+	})
+
+	var prodListener, redirectListener, testListener awselasticloadbalancingv2.ApplicationListener
+	var certificates *[]awselasticloadbalancingv2.IListenerCertificate
+	testProtocol := awselasticloadbalancingv2.ApplicationProtocol_HTTP
+
+	if props.Domain != nil {
+		// ALBのDNS名を取得
+		hostedZone := awsroute53.HostedZone_FromLookup(this, jsii.String("HostedZone"), &awsroute53.HostedZoneProviderProps{
+			DomainName: jsii.String(props.Domain.HostedZoneName),
+		})
+
+		// ACM証明書の作成
+		certificate := awscertificatemanager.NewCertificate(this, jsii.String("Certificate"), &awscertificatemanager.CertificateProps{
+			DomainName: jsii.String(props.Domain.DomainName),
+			Validation: awscertificatemanager.CertificateValidation_FromDns(hostedZone),
+		})
+		certificates = &[]awselasticloadbalancingv2.IListenerCertificate{
+			awselasticloadbalancingv2.ListenerCertificate_FromCertificateManager(certificate),
+		}
+
+		prodListener = alb.AddListener(jsii.String("ProdListener"), &awselasticloadbalancingv2.BaseApplicationListenerProps{
+			Port:         jsii.Number(443),
+			Protocol:     awselasticloadbalancingv2.ApplicationProtocol_HTTPS,
+			Certificates: certificates,
+		})
+
+		redirectListener = alb.AddListener(jsii.String("RedirectListener"), &awselasticloadbalancingv2.BaseApplicationListenerProps{
+			Port:     jsii.Number(80),
+			Protocol: awselasticloadbalancingv2.ApplicationProtocol_HTTP,
+			DefaultAction: awselasticloadbalancingv2.ListenerAction_Redirect(&awselasticloadbalancingv2.RedirectOptions{
+				Protocol: jsii.String("HTTPS"),
+				Port:     jsii.String("443"),
+			}),
+		})
+
+		testProtocol = awselasticloadbalancingv2.ApplicationProtocol_HTTPS
+
+		// ALBのDNS名をRoute53に登録
+		awsroute53.NewARecord(this, jsii.String("ARecord"), &awsroute53.ARecordProps{
+			Zone:       hostedZone,
+			RecordName: jsii.String(props.Domain.DomainName),
+			Target:     awsroute53.RecordTarget_FromAlias(awsroute53targets.NewLoadBalancerTarget(alb, nil)),
+		})
+	} else {
+		prodListener = alb.AddListener(jsii.String("ProdListener"), &awselasticloadbalancingv2.BaseApplicationListenerProps{
+			Port:     jsii.Number(80),
+			Protocol: awselasticloadbalancingv2.ApplicationProtocol_HTTP,
+			Open:     jsii.Bool(true),
+		})
+	}
+
+	// tg1
+	targetGroup1 := newTargetGroup(this, "TargetGroup1", resourceName+"-tg1", vpc, props)
+
+	prodListener.AddTargetGroups(jsii.String("TargetGroup1"), &awselasticloadbalancingv2.AddApplicationTargetGroupsProps{
+		TargetGroups: &[]awselasticloadbalancingv2.IApplicationTargetGroup{targetGroup1},
+	})
+
+	var targetGroup2 awselasticloadbalancingv2.ApplicationTargetGroup
+	if props.TestListener != nil {
+		// tg2 (Blue/Greenデプロイ用)
+		targetGroup2 = newTargetGroup(this, "TargetGroup2", resourceName+"-tg2", vpc, props)
+
+		// テストリスナー（インバウンドは上で許可した CIDR のみに絞っている）
+		testListener = alb.AddListener(jsii.String("TestListener"), &awselasticloadbalancingv2.BaseApplicationListenerProps{
+			Port:         jsii.Number(props.TestListener.Port),
+			Protocol:     testProtocol,
+			Open:         jsii.Bool(false),
+			Certificates: certificates,
+		})
+
+		testListener.AddTargetGroups(jsii.String("TargetGroup2"), &awselasticloadbalancingv2.AddApplicationTargetGroupsProps{
+			TargetGroups: &[]awselasticloadbalancingv2.IApplicationTargetGroup{targetGroup2},
+		})
+	}
+
+	return &AppNetwork{
+		Construct:        this,
+		Vpc:              vpc,
+		AlbSecurityGroup: albSecurityGroup,
+		EcsSecurityGroup: ecsSecurityGroup,
+		Alb:              alb,
+		ProdListener:     prodListener,
+		RedirectListener: redirectListener,
+		TargetGroup1:     targetGroup1,
+		TestListener:     testListener,
+		TargetGroup2:     targetGroup2,
+	}
+}
+
+func newTargetGroup(scope constructs.Construct, id string, name string, vpc awsec2.IVpc, props *AppNetworkProps) awselasticloadbalancingv2.ApplicationTargetGroup {
+	return awselasticloadbalancingv2.NewApplicationTargetGroup(scope, jsii.String(id), &awselasticloadbalancingv2.ApplicationTargetGroupProps{
+		TargetGroupName: jsii.String(name),
+		Vpc:             vpc,
+		Port:            jsii.Number(props.ContainerPort),
+		Protocol:        awselasticloadbalancingv2.ApplicationProtocol_HTTP,
+		TargetType:      awselasticloadbalancingv2.TargetType_IP,
+		HealthCheck: &awselasticloadbalancingv2.HealthCheck{
+			Path:     jsii.String(props.HealthCheckPath),
+			Interval: awscdk.Duration_Seconds(jsii.Number(60)),
+			Timeout:  awscdk.Duration_Seconds(jsii.Number(30)),
+		},
+	})
+}
+
+// エンドポイントのコンストラクトID（例: com.amazonaws.ap-northeast-1.ecr.api）
+// リージョンが未解決のトークンの場合はIDに含められないため、サービス名のみを使う
+func endpointID(region *string, name string) string {
+	if *awscdk.Token_IsUnresolved(region) {
+		return name
+	}
+	return "com.amazonaws." + *region + "." + name
+}
@@ -0,0 +1,30 @@
+package network_test
+
+import (
+	"testing"
+
+	"iaclib/network"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	"github.com/aws/jsii-runtime-go"
+)
+
+func TestUnknownInterfaceEndpointIsReported(t *testing.T) {
+	// GIVEN
+	app := awscdk.NewApp(nil)
+	stack := awscdk.NewStack(app, jsii.String("test"), nil)
+
+	// WHEN
+	network.NewAppNetwork(stack, "Network", &network.AppNetworkProps{
+		ResourceName:       "app",
+		InterfaceEndpoints: []string{"logs", "no-such-service"},
+		ContainerPort:      80,
+		HealthCheckPath:    "/",
+	})
+
+	// THEN
+	assertions.Annotations_FromStack(stack).HasError(jsii.String("/test/Network"), assertions.Match_StringLikeRegexp(jsii.String(`unknown interface endpoint "no-such-service"`)))
+	template := assertions.Template_FromStack(stack, nil)
+	template.ResourceCountIs(jsii.String("AWS::EC2::VPCEndpoint"), jsii.Number(2))
+}
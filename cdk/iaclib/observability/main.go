@@ -0,0 +1,263 @@
+package observability
+
+import (
+	"iaclib/database"
+	"iaclib/network"
+	"iaclib/service"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscloudwatch"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscloudwatchactions"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awselasticloadbalancingv2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awssns"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awssnssubscriptions"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+type ObservabilityProps struct {
+	// 各リソースの名前の接頭辞
+	ResourceName string
+	Network      *network.AppNetwork
+	Service      *service.FargateWebService
+	Database     *database.PostgresDatabase
+	// アラームのしきい値（0 の項目はアラームを作らない）
+	Thresholds AlarmThresholds
+	// アラームを通知するメールアドレス
+	AlarmEmails []string
+}
+
+// AlarmThresholds は運用中に通知するアラームのしきい値
+type AlarmThresholds struct {
+	// ALB とターゲットの 5xx 応答の割合（%）
+	Max5xxRatePercent int
+	// ターゲットの応答時間の p99（ミリ秒）
+	MaxResponseTimeMillis int
+	// ECS サービスの CPU / メモリ使用率（%）
+	MaxServiceCpuPercent    int
+	MaxServiceMemoryPercent int
+	// RDS の CPU 使用率（%）と接続数
+	MaxDBCpuPercent  int
+	MaxDBConnections int
+	// RDS の空きストレージの下限（MiB）
+	MinDBFreeStorageMiB int
+	// RDS の読み取り / 書き込みのレイテンシ（ミリ秒）
+	MaxDBReadLatencyMillis  int
+	MaxDBWriteLatencyMillis int
+}
+
+// Observability は ALB、ECS、RDS のダッシュボードと、SNS に通知するアラーム
+type Observability struct {
+	constructs.Construct
+	Dashboard  awscloudwatch.Dashboard
+	AlarmTopic awssns.Topic
+	Alarms     []awscloudwatch.Alarm
+}
+
+// アラームとダッシュボードのメトリクスの期間（分）
+const periodMinutes = 5
+
+func NewObservability(scope constructs.Construct, id string, props *ObservabilityProps) *Observability {
+	this := constructs.NewConstruct(scope, &id)
+	resourceName := props.ResourceName
+	alb := props.Network.Alb.Metrics()
+	ecsService := props.Service.Service
+	db := props.Database
+	thresholds := props.Thresholds
+	period := awscdk.Duration_Minutes(jsii.Number(periodMinutes))
+	sum := func() *awscloudwatch.MetricOptions {
+		return &awscloudwatch.MetricOptions{Period: period, Statistic: jsii.String("Sum")}
+	}
+	average := func() *awscloudwatch.MetricOptions {
+		return &awscloudwatch.MetricOptions{Period: period, Statistic: jsii.String("Average")}
+	}
+
+	topic := awssns.NewTopic(this, jsii.String("AlarmTopic"), &awssns.TopicProps{
+		TopicName: jsii.String(resourceName + "-alarms"),
+	})
+	for _, email := range props.AlarmEmails {
+		topic.AddSubscription(awssnssubscriptions.NewEmailSubscription(jsii.String(email), nil))
+	}
+
+	// ALB
+	requests := alb.RequestCount(sum())
+	elb5xx := alb.HttpCodeElb(awselasticloadbalancingv2.HttpCodeElb_ELB_5XX_COUNT, sum())
+	target5xx := alb.HttpCodeTarget(awselasticloadbalancingv2.HttpCodeTarget_TARGET_5XX_COUNT, sum())
+	// 5xx が 1 件も無い期間はメトリクスが出力されないため、0 で埋めてから足す
+	errorRate := awscloudwatch.NewMathExpression(&awscloudwatch.MathExpressionProps{
+		Expression: jsii.String("IF(requests > 0, 100 * (FILL(elb5xx, 0) + FILL(target5xx, 0)) / requests, 0)"),
+		UsingMetrics: &map[string]awscloudwatch.IMetric{
+			"requests":  requests,
+			"elb5xx":    elb5xx,
+			"target5xx": target5xx,
+		},
+		Label:  jsii.String("5xx rate (%)"),
+		Period: period,
+	})
+	latency := func(statistic string) awscloudwatch.Metric {
+		return alb.TargetResponseTime(&awscloudwatch.MetricOptions{
+			Period:    period,
+			Statistic: jsii.String(statistic),
+			Label:     jsii.String(statistic),
+		})
+	}
+
+	// ECS（実行中のタスク数は Container Insights のメトリクス）
+	serviceCpu := ecsService.MetricCpuUtilization(average())
+	serviceMemory := ecsService.MetricMemoryUtilization(average())
+	taskCount := func(metricName string) awscloudwatch.Metric {
+		return awscloudwatch.NewMetric(&awscloudwatch.MetricProps{
+			Namespace:  jsii.String("ECS/ContainerInsights"),
+			MetricName: jsii.String(metricName),
+			DimensionsMap: &map[string]*string{
+				"ClusterName": props.Service.Cluster.ClusterName(),
+				"ServiceName": ecsService.ServiceName(),
+			},
+			Period:    period,
+			Statistic: jsii.String("Average"),
+		})
+	}
+
+	// RDS
+	dbCpu := db.Metric("CPUUtilization", average())
+	dbConnections := db.Metric("DatabaseConnections", &awscloudwatch.MetricOptions{
+		Period:    period,
+		Statistic: jsii.String("Maximum"),
+	})
+	dbFreeStorage := db.MetricFreeStorage(&awscloudwatch.MetricOptions{
+		Period:    period,
+		Statistic: jsii.String("Minimum"),
+	})
+	dbReadLatency := db.Metric("ReadLatency", average())
+	dbWriteLatency := db.Metric("WriteLatency", average())
+
+	alarms := newAlarms(this, resourceName, topic, []alarmSpec{
+		{"Alb5xxRateAlarm", "alb-5xx-rate", "5xx responses from the ALB and targets exceed the threshold", errorRate, thresholds.Max5xxRatePercent, 1, awscloudwatch.ComparisonOperator_GREATER_THAN_THRESHOLD},
+		{"ResponseTimeAlarm", "response-time", "p99 response time of the targets exceeds the threshold", latency("p99"), thresholds.MaxResponseTimeMillis, 0.001, awscloudwatch.ComparisonOperator_GREATER_THAN_THRESHOLD},
+		{"ServiceCpuAlarm", "ecs-cpu", "CPU utilization of the ECS service exceeds the threshold", serviceCpu, thresholds.MaxServiceCpuPercent, 1, awscloudwatch.ComparisonOperator_GREATER_THAN_THRESHOLD},
+		{"ServiceMemoryAlarm", "ecs-memory", "Memory utilization of the ECS service exceeds the threshold", serviceMemory, thresholds.MaxServiceMemoryPercent, 1, awscloudwatch.ComparisonOperator_GREATER_THAN_THRESHOLD},
+		{"DatabaseCpuAlarm", "rds-cpu", "CPU utilization of the database exceeds the threshold", dbCpu, thresholds.MaxDBCpuPercent, 1, awscloudwatch.ComparisonOperator_GREATER_THAN_THRESHOLD},
+		{"DatabaseConnectionsAlarm", "rds-connections", "Connections to the database exceed the threshold", dbConnections, thresholds.MaxDBConnections, 1, awscloudwatch.ComparisonOperator_GREATER_THAN_THRESHOLD},
+		// 空きストレージの単位はバイト
+		{"DatabaseFreeStorageAlarm", "rds-free-storage", "Free storage of the database falls below the threshold", dbFreeStorage, thresholds.MinDBFreeStorageMiB, 1024 * 1024, awscloudwatch.ComparisonOperator_LESS_THAN_THRESHOLD},
+		// ReadLatency / WriteLatency の単位は秒
+		{"DatabaseReadLatencyAlarm", "rds-read-latency", "Read latency of the database exceeds the threshold", dbReadLatency, thresholds.MaxDBReadLatencyMillis, 0.001, awscloudwatch.ComparisonOperator_GREATER_THAN_THRESHOLD},
+		{"DatabaseWriteLatencyAlarm", "rds-write-latency", "Write latency of the database exceeds the threshold", dbWriteLatency, thresholds.MaxDBWriteLatencyMillis, 0.001, awscloudwatch.ComparisonOperator_GREATER_THAN_THRESHOLD},
+	})
+
+	// ブルーグリーンデプロイでは本番のターゲットグループが入れ替わるため、両方の状態を表示する
+	var healthyHosts, unhealthyHosts []awscloudwatch.IMetric
+	for _, target := range []struct {
+		label       string
+		targetGroup awselasticloadbalancingv2.ApplicationTargetGroup
+	}{
+		{"tg1", props.Network.TargetGroup1},
+		{"tg2", props.Network.TargetGroup2},
+	} {
+		tg := target.targetGroup
+		if tg == nil {
+			continue
+		}
+		healthyHosts = append(healthyHosts, tg.Metrics().HealthyHostCount(&awscloudwatch.MetricOptions{
+			Period:    period,
+			Statistic: jsii.String("Minimum"),
+			Label:     jsii.String(target.label + " healthy"),
+		}))
+		unhealthyHosts = append(unhealthyHosts, tg.Metrics().UnhealthyHostCount(&awscloudwatch.MetricOptions{
+			Period:    period,
+			Statistic: jsii.String("Maximum"),
+			Label:     jsii.String(target.label + " unhealthy"),
+		}))
+	}
+
+	dashboard := awscloudwatch.NewDashboard(this, jsii.String("Dashboard"), &awscloudwatch.DashboardProps{
+		DashboardName:   jsii.String(resourceName + "-dashboard"),
+		DefaultInterval: awscdk.Duration_Hours(jsii.Number(3)),
+	})
+	alarmList := make([]awscloudwatch.IAlarm, 0, len(alarms))
+	for _, alarm := range alarms {
+		alarmList = append(alarmList, alarm)
+	}
+	dashboard.AddWidgets(awscloudwatch.NewAlarmStatusWidget(&awscloudwatch.AlarmStatusWidgetProps{
+		Title:  jsii.String("Alarms"),
+		Alarms: &alarmList,
+		Width:  jsii.Number(24),
+	}))
+	dashboard.AddWidgets(
+		graph("ALB requests", []awscloudwatch.IMetric{requests}, nil),
+		graph("ALB 4xx / 5xx", []awscloudwatch.IMetric{
+			alb.HttpCodeElb(awselasticloadbalancingv2.HttpCodeElb_ELB_4XX_COUNT, sum()),
+			alb.HttpCodeTarget(awselasticloadbalancingv2.HttpCodeTarget_TARGET_4XX_COUNT, sum()),
+			elb5xx,
+			target5xx,
+		}, nil),
+		graph("Target response time (seconds)", []awscloudwatch.IMetric{latency("p50"), latency("p90"), latency("p99")}, nil),
+		graph("Target health", healthyHosts, unhealthyHosts),
+	)
+	dashboard.AddWidgets(
+		graph("ECS CPU / memory (%)", []awscloudwatch.IMetric{serviceCpu, serviceMemory}, nil),
+		graph("ECS tasks", []awscloudwatch.IMetric{taskCount("RunningTaskCount"), taskCount("DesiredTaskCount")}, nil),
+	)
+	dashboard.AddWidgets(
+		graph("RDS CPU (%)", []awscloudwatch.IMetric{dbCpu}, nil),
+		graph("RDS connections", []awscloudwatch.IMetric{dbConnections}, nil),
+		graph("RDS free storage (bytes)", []awscloudwatch.IMetric{dbFreeStorage}, nil),
+		graph("RDS read / write latency (seconds)", []awscloudwatch.IMetric{dbReadLatency, dbWriteLatency}, nil),
+	)
+
+	return &Observability{
+		Construct:  this,
+		Dashboard:  dashboard,
+		AlarmTopic: topic,
+		Alarms:     alarms,
+	}
+}
+
+type alarmSpec struct {
+	id          string
+	name        string
+	description string
+	metric      awscloudwatch.IMetric
+	threshold   int
+	// しきい値をメトリクスの単位に変換する係数（ミリ秒 → 秒など）
+	scale      float64
+	comparison awscloudwatch.ComparisonOperator
+}
+
+// しきい値が 0 のアラームは作らない。アラームと復旧の両方を通知する
+func newAlarms(scope constructs.Construct, resourceName string, topic awssns.ITopic, specs []alarmSpec) []awscloudwatch.Alarm {
+	action := awscloudwatchactions.NewSnsAction(topic)
+	var alarms []awscloudwatch.Alarm
+	for _, spec := range specs {
+		if spec.threshold <= 0 {
+			continue
+		}
+		alarm := awscloudwatch.NewAlarm(scope, jsii.String(spec.id), &awscloudwatch.AlarmProps{
+			AlarmName:          jsii.String(resourceName + "-" + spec.name),
+			AlarmDescription:   jsii.String(spec.description),
+			Metric:             spec.metric,
+			Threshold:          jsii.Number(float64(spec.threshold) * spec.scale),
+			ComparisonOperator: spec.comparison,
+			EvaluationPeriods:  jsii.Number(3),
+			DatapointsToAlarm:  jsii.Number(2),
+			TreatMissingData:   awscloudwatch.TreatMissingData_NOT_BREACHING,
+		})
+		alarm.AddAlarmAction(action)
+		alarm.AddOkAction(action)
+		alarms = append(alarms, alarm)
+	}
+	return alarms
+}
+
+func graph(title string, left []awscloudwatch.IMetric, right []awscloudwatch.IMetric) awscloudwatch.GraphWidget {
+	props := &awscloudwatch.GraphWidgetProps{
+		Title:  jsii.String(title),
+		Left:   &left,
+		Width:  jsii.Number(6),
+		Height: jsii.Number(6),
+	}
+	if len(right) > 0 {
+		props.Right = &right
+	}
+	return awscloudwatch.NewGraphWidget(props)
+}
@@ -0,0 +1,324 @@
+package security
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsefs"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslogs"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsrds"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// RuleID はコンプライアンスチェックのルール
+type RuleID string
+
+const (
+	// RDS、EBS、EFS のストレージが暗号化されていない（S3、ECR、SQS は既定で暗号化される）
+	RuleEncryptedStorage RuleID = "EncryptedStorage"
+	// セキュリティグループがインターネットからの通信を許可している（ALB の 80 / 443 を除く）
+	RulePublicIngress RuleID = "PublicIngress"
+	// IAM ポリシーのアクションやリソースがワイルドカードになっている
+	RuleWildcardIam RuleID = "WildcardIam"
+	// ロググループに保持期間が無い
+	RuleLogRetention RuleID = "LogRetention"
+	// データを持つリソースが削除時に一緒に消える（RemovalPolicy_DESTROY）
+	RuleStatefulRemovalPolicy RuleID = "StatefulRemovalPolicy"
+	// RDS の削除保護が無効になっている
+	RuleDeletionProtection RuleID = "DeletionProtection"
+)
+
+// エラーは synth を失敗させ、警告は表示のみ
+var errorRules = []RuleID{
+	RuleEncryptedStorage,
+	RulePublicIngress,
+	RuleWildcardIam,
+	RuleStatefulRemovalPolicy,
+}
+
+// 削除時にデータが失われるリソース
+var statefulResourceTypes = []string{
+	"AWS::RDS::DBInstance",
+	"AWS::RDS::DBCluster",
+	"AWS::S3::Bucket",
+	"AWS::DynamoDB::Table",
+	"AWS::EFS::FileSystem",
+	"AWS::ECR::Repository",
+}
+
+// リソースを指定できないため、Resource が * でも許容するアクション
+var resourceWildcardActions = []string{
+	"ecr:GetAuthorizationToken",
+	"ecs:RegisterTaskDefinition",
+	"ecs:DescribeTaskDefinition",
+	"ec2:Describe*",
+	"elasticloadbalancing:Describe*",
+	"cloudwatch:PutMetricData",
+	"xray:PutTraceSegments",
+	"xray:PutTelemetryRecords",
+}
+
+// インターネットから許可してよい ALB のポート
+var publicAlbPorts = []float64{80, 443}
+
+// 渡す先のサービスを限定していれば、Resource が * でも許容する iam:PassRole の条件キー
+const passedToServiceConditionKey = "iam:PassedToService"
+
+const (
+	suppressionMetadataType        = "iaclib:compliance:suppress"
+	publicLoadBalancerMetadataType = "iaclib:compliance:public-load-balancer"
+)
+
+// Suppress は scope 以下のリソースで rule の指摘を抑制する。reason には抑制する理由を必ず書く
+func Suppress(scope constructs.IConstruct, rule RuleID, reason string) {
+	scope.Node().AddMetadata(jsii.String(suppressionMetadataType), map[string]string{
+		"rule":   string(rule),
+		"reason": reason,
+	}, nil)
+}
+
+// MarkPublicLoadBalancer は scope をインターネットに公開する ALB のセキュリティグループとして登録する。
+// 登録したセキュリティグループでは、インターネットからの 80 / 443 を指摘しない
+func MarkPublicLoadBalancer(scope constructs.IConstruct) {
+	scope.Node().AddMetadata(jsii.String(publicLoadBalancerMetadataType), true, nil)
+}
+
+type complianceChecks struct {
+	errorRules []RuleID
+}
+
+// NewComplianceChecks は構成ツリーのリソースを検査し、指摘をエラーか警告として報告する Aspect を返す
+func NewComplianceChecks() awscdk.IAspect {
+	return &complianceChecks{errorRules: errorRules}
+}
+
+func (c *complianceChecks) Visit(node constructs.IConstruct) {
+	for _, rule := range suppressedRules(node) {
+		if rule.reason == "" {
+			awscdk.Annotations_Of(node).AddError(jsii.String(fmt.Sprintf("[%s] suppression requires a justification", rule.id)))
+		}
+	}
+
+	switch resource := node.(type) {
+	case awsrds.CfnDBInstance:
+		c.checkDBInstance(resource)
+	case awsrds.CfnDBCluster:
+		if isDisabled(resource, resource.StorageEncrypted()) {
+			c.report(resource, RuleEncryptedStorage, "DB cluster storage is not encrypted")
+		}
+		if isDisabled(resource, resource.DeletionProtection()) {
+			c.report(resource, RuleDeletionProtection, "DB cluster deletion protection is disabled")
+		}
+	case awsec2.CfnVolume:
+		if isDisabled(resource, resource.Encrypted()) {
+			c.report(resource, RuleEncryptedStorage, "EBS volume is not encrypted")
+		}
+	case awsefs.CfnFileSystem:
+		if isDisabled(resource, resource.Encrypted()) {
+			c.report(resource, RuleEncryptedStorage, "EFS file system is not encrypted")
+		}
+	case awsec2.CfnSecurityGroup:
+		if resource.SecurityGroupIngress() != nil {
+			rules, _ := resolve(resource, resource.SecurityGroupIngress()).([]interface{})
+			for _, rule := range rules {
+				if ingress, ok := rule.(map[string]interface{}); ok {
+					c.checkIngress(resource, ingress)
+				}
+			}
+		}
+	case awsec2.CfnSecurityGroupIngress:
+		c.checkIngress(resource, map[string]interface{}{
+			"cidrIp":   resolve(resource, resource.CidrIp()),
+			"cidrIpv6": resolve(resource, resource.CidrIpv6()),
+			"fromPort": resolve(resource, resource.FromPort()),
+			"toPort":   resolve(resource, resource.ToPort()),
+		})
+	case awsiam.CfnPolicy:
+		c.checkPolicyDocument(resource, resource.PolicyDocument())
+	case awsiam.CfnManagedPolicy:
+		c.checkPolicyDocument(resource, resource.PolicyDocument())
+	case awsiam.CfnRole:
+		if resource.Policies() != nil {
+			policies, _ := resolve(resource, resource.Policies()).([]interface{})
+			for _, policy := range policies {
+				if p, ok := policy.(map[string]interface{}); ok {
+					c.checkPolicyDocument(resource, p["PolicyDocument"])
+				}
+			}
+		}
+	case awslogs.CfnLogGroup:
+		if resource.RetentionInDays() == nil {
+			c.report(resource, RuleLogRetention, "log group has no retention period")
+		}
+	}
+
+	if resource, ok := node.(awscdk.CfnResource); ok && slices.Contains(statefulResourceTypes, *resource.CfnResourceType()) && !isClusterInstance(node) {
+		if resource.CfnOptions().DeletionPolicy() == awscdk.CfnDeletionPolicy_DELETE {
+			c.report(resource, RuleStatefulRemovalPolicy, fmt.Sprintf("%s is deleted with the stack (RemovalPolicy DESTROY)", *resource.CfnResourceType()))
+		}
+	}
+}
+
+func (c *complianceChecks) checkDBInstance(instance awsrds.CfnDBInstance) {
+	// Aurora のインスタンスはクラスターの設定に従う
+	if isClusterInstance(instance) {
+		return
+	}
+	if isDisabled(instance, instance.StorageEncrypted()) {
+		c.report(instance, RuleEncryptedStorage, "DB instance storage is not encrypted")
+	}
+	if isDisabled(instance, instance.DeletionProtection()) {
+		c.report(instance, RuleDeletionProtection, "DB instance deletion protection is disabled")
+	}
+}
+
+// Aurora のインスタンスはデータを持たず、削除ポリシーや暗号化はクラスターで決まる
+func isClusterInstance(node constructs.IConstruct) bool {
+	instance, ok := node.(awsrds.CfnDBInstance)
+	return ok && instance.DbClusterIdentifier() != nil
+}
+
+// ingress は L1 のプロパティ名（cidrIp、fromPort など）のマップ
+func (c *complianceChecks) checkIngress(node constructs.IConstruct, ingress map[string]interface{}) {
+	if ingress["cidrIp"] != "0.0.0.0/0" && ingress["cidrIpv6"] != "::/0" {
+		return
+	}
+	fromPort, _ := ingress["fromPort"].(float64)
+	toPort, _ := ingress["toPort"].(float64)
+	if isPublicLoadBalancer(node) && fromPort == toPort && slices.Contains(publicAlbPorts, fromPort) {
+		return
+	}
+	c.report(node, RulePublicIngress, fmt.Sprintf("ingress from anywhere on port %v-%v", ingress["fromPort"], ingress["toPort"]))
+}
+
+func (c *complianceChecks) checkPolicyDocument(node constructs.IConstruct, document interface{}) {
+	resolved, _ := resolve(node, document).(map[string]interface{})
+	statements, _ := resolved["Statement"].([]interface{})
+	for _, s := range statements {
+		statement, ok := s.(map[string]interface{})
+		if !ok || statement["Effect"] != "Allow" {
+			continue
+		}
+		actions := stringList(statement["Action"])
+		for _, action := range actions {
+			if action == "*" || strings.HasSuffix(action, ":*") {
+				c.report(node, RuleWildcardIam, fmt.Sprintf("policy allows every action (%s)", action))
+			}
+		}
+		if slices.Contains(stringList(statement["Resource"]), "*") && !allResourceWildcardActions(actions) && !isScopedPassRole(statement, actions) {
+			c.report(node, RuleWildcardIam, fmt.Sprintf("policy allows %s on every resource", strings.Join(actions, ", ")))
+		}
+	}
+}
+
+func allResourceWildcardActions(actions []string) bool {
+	for _, action := range actions {
+		if !slices.ContainsFunc(resourceWildcardActions, func(pattern string) bool {
+			return action == pattern || strings.HasSuffix(pattern, "*") && strings.HasPrefix(action, strings.TrimSuffix(pattern, "*"))
+		}) {
+			return false
+		}
+	}
+	return len(actions) > 0
+}
+
+// iam:PassRole だけを許可し、渡す先のサービスを条件で限定しているステートメント
+func isScopedPassRole(statement map[string]interface{}, actions []string) bool {
+	if len(actions) == 0 || slices.ContainsFunc(actions, func(action string) bool { return action != "iam:PassRole" }) {
+		return false
+	}
+	conditions, _ := statement["Condition"].(map[string]interface{})
+	for _, condition := range conditions {
+		if keys, ok := condition.(map[string]interface{}); ok && len(stringList(keys[passedToServiceConditionKey])) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// 抑制されていない指摘をルールのレベルで報告する
+func (c *complianceChecks) report(node constructs.IConstruct, rule RuleID, message string) {
+	for _, suppressed := range suppressedRules(node) {
+		if suppressed.id == rule && suppressed.reason != "" {
+			return
+		}
+	}
+	text := fmt.Sprintf("[%s] %s", rule, message)
+	if slices.Contains(c.errorRules, rule) {
+		awscdk.Annotations_Of(node).AddError(jsii.String(text))
+		return
+	}
+	awscdk.Annotations_Of(node).AddWarningV2(jsii.String("iaclib-compliance:"+string(rule)), jsii.String(text))
+}
+
+type suppression struct {
+	id     RuleID
+	reason string
+}
+
+// node と親のコンストラクトに付けた抑制
+func suppressedRules(node constructs.IConstruct) []suppression {
+	var rules []suppression
+	for _, scope := range *node.Node().Scopes() {
+		for _, entry := range *scope.Node().Metadata() {
+			if *entry.Type != suppressionMetadataType {
+				continue
+			}
+			data, _ := entry.Data.(map[string]interface{})
+			id, _ := data["rule"].(string)
+			reason, _ := data["reason"].(string)
+			// 抑制の理由が無い場合は、抑制を付けたコンストラクトで1回だけ報告する
+			if scope != node && reason == "" {
+				continue
+			}
+			rules = append(rules, suppression{id: RuleID(id), reason: strings.TrimSpace(reason)})
+		}
+	}
+	return rules
+}
+
+// node か親のコンストラクトが MarkPublicLoadBalancer で登録されている
+func isPublicLoadBalancer(node constructs.IConstruct) bool {
+	for _, scope := range *node.Node().Scopes() {
+		for _, entry := range *scope.Node().Metadata() {
+			if *entry.Type == publicLoadBalancerMetadataType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func stringList(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var list []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+// 値が未設定か false の場合に true を返す。パラメーターや条件などで synth 時に決まらない値は指摘しない
+func isDisabled(node constructs.IConstruct, value interface{}) bool {
+	switch v := resolve(node, value).(type) {
+	case nil:
+		return true
+	case bool:
+		return !v
+	case string:
+		return v == "false"
+	}
+	return false
+}
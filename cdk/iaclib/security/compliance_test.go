@@ -0,0 +1,121 @@
+package security_test
+
+import (
+	"testing"
+
+	"iaclib/security"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslogs"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsrds"
+	"github.com/aws/jsii-runtime-go"
+)
+
+func TestComplianceChecks(t *testing.T) {
+	// GIVEN
+	app := awscdk.NewApp(nil)
+	stack := awscdk.NewStack(app, jsii.String("test"), nil)
+	awscdk.Aspects_Of(app).Add(security.NewComplianceChecks(), nil)
+	vpc := awsec2.NewVpc(stack, jsii.String("Vpc"), nil)
+
+	// WHEN
+	awsrds.NewDatabaseInstance(stack, jsii.String("Database"), &awsrds.DatabaseInstanceProps{
+		Engine:           awsrds.DatabaseInstanceEngine_Postgres(&awsrds.PostgresInstanceEngineProps{Version: awsrds.PostgresEngineVersion_VER_16_4()}),
+		Vpc:              vpc,
+		StorageEncrypted: jsii.Bool(false),
+		RemovalPolicy:    awscdk.RemovalPolicy_DESTROY,
+	})
+
+	awsrds.NewDatabaseCluster(stack, jsii.String("Cluster"), &awsrds.DatabaseClusterProps{
+		Engine:        awsrds.DatabaseClusterEngine_AuroraPostgres(&awsrds.AuroraPostgresClusterEngineProps{Version: awsrds.AuroraPostgresEngineVersion_VER_16_4()}),
+		Writer:        awsrds.ClusterInstance_ServerlessV2(jsii.String("writer"), nil),
+		Vpc:           vpc,
+		RemovalPolicy: awscdk.RemovalPolicy_SNAPSHOT,
+	})
+
+	// 登録したセキュリティグループだけを ALB として扱い、コンストラクトID では判断しない
+	alb := awsec2.NewSecurityGroup(stack, jsii.String("PublicSecurityGroup"), &awsec2.SecurityGroupProps{Vpc: vpc})
+	security.MarkPublicLoadBalancer(alb)
+	alb.AddIngressRule(awsec2.Peer_AnyIpv4(), awsec2.Port_Tcp(jsii.Number(443)), nil, nil)
+	unmarked := awsec2.NewSecurityGroup(stack, jsii.String("AlbSecurityGroup"), &awsec2.SecurityGroupProps{Vpc: vpc})
+	unmarked.AddIngressRule(awsec2.Peer_AnyIpv4(), awsec2.Port_Tcp(jsii.Number(443)), nil, nil)
+	ssh := awsec2.NewSecurityGroup(stack, jsii.String("SshSecurityGroup"), &awsec2.SecurityGroupProps{Vpc: vpc})
+	ssh.AddIngressRule(awsec2.Peer_AnyIpv4(), awsec2.Port_Tcp(jsii.Number(22)), nil, nil)
+
+	role := awsiam.NewRole(stack, jsii.String("Role"), &awsiam.RoleProps{
+		AssumedBy: awsiam.NewServicePrincipal(jsii.String("ecs-tasks.amazonaws.com"), nil),
+	})
+	role.AddToPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("s3:*"),
+		Resources: jsii.Strings("arn:aws:s3:::bucket/*"),
+	}))
+	scopedRole := awsiam.NewRole(stack, jsii.String("ScopedRole"), &awsiam.RoleProps{
+		AssumedBy: awsiam.NewServicePrincipal(jsii.String("ecs-tasks.amazonaws.com"), nil),
+	})
+	scopedRole.AddToPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("ecr:GetAuthorizationToken", "elasticloadbalancing:DescribeListeners"),
+		Resources: jsii.Strings("*"),
+	}))
+
+	passRole := awsiam.NewRole(stack, jsii.String("PassRole"), &awsiam.RoleProps{
+		AssumedBy: awsiam.NewServicePrincipal(jsii.String("codepipeline.amazonaws.com"), nil),
+	})
+	passRole.AddToPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:    jsii.Strings("iam:PassRole"),
+		Resources:  jsii.Strings("*"),
+		Conditions: &map[string]interface{}{"StringEqualsIfExists": map[string]interface{}{"iam:PassedToService": []string{"ecs-tasks.amazonaws.com"}}},
+	}))
+	unscopedPassRole := awsiam.NewRole(stack, jsii.String("UnscopedPassRole"), &awsiam.RoleProps{
+		AssumedBy: awsiam.NewServicePrincipal(jsii.String("codepipeline.amazonaws.com"), nil),
+	})
+	unscopedPassRole.AddToPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("iam:PassRole"),
+		Resources: jsii.Strings("*"),
+	}))
+
+	// パラメーターで決まる値は synth 時に判断できないため指摘しない
+	encrypted := awscdk.NewCfnParameter(stack, jsii.String("Encrypted"), &awscdk.CfnParameterProps{AllowedValues: jsii.Strings("true", "false")})
+	awsrds.NewCfnDBInstance(stack, jsii.String("ParameterizedDatabase"), &awsrds.CfnDBInstanceProps{
+		Engine:             jsii.String("postgres"),
+		DbInstanceClass:    jsii.String("db.t4g.micro"),
+		StorageEncrypted:   encrypted.Value(),
+		DeletionProtection: encrypted.Value(),
+	})
+
+	awslogs.NewLogGroup(stack, jsii.String("LogGroup"), &awslogs.LogGroupProps{
+		Retention: awslogs.RetentionDays_INFINITE,
+	})
+
+	suppressed := awsec2.NewSecurityGroup(stack, jsii.String("SuppressedSecurityGroup"), &awsec2.SecurityGroupProps{Vpc: vpc})
+	suppressed.AddIngressRule(awsec2.Peer_AnyIpv4(), awsec2.Port_Tcp(jsii.Number(8080)), nil, nil)
+	security.Suppress(suppressed, security.RulePublicIngress, "test listener")
+	unjustified := awsec2.NewSecurityGroup(stack, jsii.String("UnjustifiedSecurityGroup"), &awsec2.SecurityGroupProps{Vpc: vpc})
+	security.Suppress(unjustified, security.RulePublicIngress, " ")
+
+	// THEN
+	annotations := assertions.Annotations_FromStack(stack)
+	errorWith := func(path string, pattern string) {
+		t.Helper()
+		annotations.HasError(jsii.String(path), assertions.Match_StringLikeRegexp(jsii.String(pattern)))
+	}
+	errorWith("/test/Database/Resource", `^\[EncryptedStorage\]`)
+	errorWith("/test/Database/Resource", `^\[StatefulRemovalPolicy\]`)
+	annotations.HasWarning(jsii.String("/test/Database/Resource"), assertions.Match_StringLikeRegexp(jsii.String(`^\[DeletionProtection\]`)))
+	errorWith("/test/Cluster/Resource", `^\[EncryptedStorage\]`)
+	annotations.HasNoError(jsii.String("/test/Cluster/writer/Resource"), assertions.Match_AnyValue())
+	errorWith("/test/SshSecurityGroup/Resource", `^\[PublicIngress\] .* 22-22`)
+	annotations.HasNoError(jsii.String("/test/PublicSecurityGroup/Resource"), assertions.Match_AnyValue())
+	errorWith("/test/AlbSecurityGroup/Resource", `^\[PublicIngress\] .* 443-443`)
+	errorWith("/test/Role/DefaultPolicy/Resource", `^\[WildcardIam\] .*s3:\*`)
+	annotations.HasNoError(jsii.String("/test/ScopedRole/DefaultPolicy/Resource"), assertions.Match_AnyValue())
+	annotations.HasNoError(jsii.String("/test/PassRole/DefaultPolicy/Resource"), assertions.Match_AnyValue())
+	errorWith("/test/UnscopedPassRole/DefaultPolicy/Resource", `^\[WildcardIam\] .*iam:PassRole on every resource`)
+	annotations.HasNoError(jsii.String("/test/ParameterizedDatabase"), assertions.Match_AnyValue())
+	annotations.HasNoWarning(jsii.String("/test/ParameterizedDatabase"), assertions.Match_AnyValue())
+	annotations.HasWarning(jsii.String("/test/LogGroup/Resource"), assertions.Match_StringLikeRegexp(jsii.String(`^\[LogRetention\]`)))
+	annotations.HasNoError(jsii.String("/test/SuppressedSecurityGroup/Resource"), assertions.Match_AnyValue())
+	errorWith("/test/UnjustifiedSecurityGroup", `suppression requires a justification`)
+}
@@ -0,0 +1,47 @@
+package security
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// 全サービスや IAM 全体を操作できる AWS 管理ポリシー（AdministratorAccess、PowerUserAccess、xxxFullAccess など）
+var adminManagedPolicyPattern = regexp.MustCompile(`:iam::aws:policy/(?:[\w+=,.@-]+/)*(AdministratorAccess|PowerUserAccess|[\w+=,.@-]*FullAccess)\b`)
+
+type managedPolicyGuard struct {
+	pattern *regexp.Regexp
+}
+
+// NewManagedPolicyGuard は管理者相当の AWS 管理ポリシーをアタッチしたロールをエラーにし、synth を失敗させる Aspect を返す。
+// 権限は各コンストラクトで対象のリソースに絞って付与する
+func NewManagedPolicyGuard() awscdk.IAspect {
+	return &managedPolicyGuard{pattern: adminManagedPolicyPattern}
+}
+
+func (g *managedPolicyGuard) Visit(node constructs.IConstruct) {
+	role, ok := node.(awsiam.CfnRole)
+	if !ok || role.ManagedPolicyArns() == nil {
+		return
+	}
+	for _, name := range g.adminManagedPolicies(role, role.ManagedPolicyArns()) {
+		awscdk.Annotations_Of(role).AddError(jsii.String(fmt.Sprintf("%s must not be attached; grant permissions scoped to the resources the role uses", name)))
+	}
+}
+
+func (g *managedPolicyGuard) adminManagedPolicies(role awsiam.CfnRole, arns *[]*string) []string {
+	resolved, err := json.Marshal(resolve(role, arns))
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, m := range g.pattern.FindAllStringSubmatch(string(resolved), -1) {
+		names = append(names, m[1])
+	}
+	return names
+}
@@ -0,0 +1,41 @@
+package security_test
+
+import (
+	"testing"
+
+	"iaclib/security"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/jsii-runtime-go"
+)
+
+func TestManagedPolicyGuard(t *testing.T) {
+	// GIVEN
+	app := awscdk.NewApp(nil)
+	stack := awscdk.NewStack(app, jsii.String("test"), nil)
+	awscdk.Aspects_Of(stack).Add(security.NewManagedPolicyGuard(), nil)
+
+	// WHEN
+	for id, policy := range map[string]string{
+		"Admin":     "AdministratorAccess",
+		"Ecr":       "AmazonEC2ContainerRegistryFullAccess",
+		"Execution": "service-role/AmazonECSTaskExecutionRolePolicy",
+		"ReadOnly":  "ReadOnlyAccess",
+	} {
+		awsiam.NewRole(stack, jsii.String(id), &awsiam.RoleProps{
+			AssumedBy: awsiam.NewServicePrincipal(jsii.String("ecs-tasks.amazonaws.com"), nil),
+			ManagedPolicies: &[]awsiam.IManagedPolicy{
+				awsiam.ManagedPolicy_FromAwsManagedPolicyName(jsii.String(policy)),
+			},
+		})
+	}
+
+	// THEN
+	annotations := assertions.Annotations_FromStack(stack)
+	annotations.HasError(jsii.String("/test/Admin/Resource"), assertions.Match_StringLikeRegexp(jsii.String("AdministratorAccess")))
+	annotations.HasError(jsii.String("/test/Ecr/Resource"), assertions.Match_StringLikeRegexp(jsii.String("AmazonEC2ContainerRegistryFullAccess")))
+	annotations.HasNoError(jsii.String("/test/Execution/Resource"), assertions.Match_AnyValue())
+	annotations.HasNoError(jsii.String("/test/ReadOnly/Resource"), assertions.Match_AnyValue())
+}
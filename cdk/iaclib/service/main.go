@@ -0,0 +1,349 @@
+package service
+
+import (
+	"iaclib/monitoring"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsapplicationautoscaling"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscloudwatch"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsecr"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsecs"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awselasticloadbalancingv2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslogs"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+type FargateWebServiceProps struct {
+	// 各リソースの名前の接頭辞
+	ResourceName  string
+	Vpc           awsec2.IVpc
+	SecurityGroup awsec2.ISecurityGroup
+	// 指定しない場合はサービス用のクラスターを作成する
+	Cluster awsecs.ICluster
+	// true の場合、作成するクラスターの Container Insights を有効にする（実行中のタスク数などのメトリクスを取得する）
+	ContainerInsights bool
+	// サービスを登録する既存のターゲットグループ（Routing とどちらか一方を指定する）
+	TargetGroup awselasticloadbalancingv2.ApplicationTargetGroup
+	// 指定した場合はターゲットグループを作成し、リスナールールで振り分ける
+	Routing *RoutingProps
+	// イメージを取得する ECR リポジトリ名（latest タグを使う）
+	RepositoryName string
+	ContainerName  string
+	ContainerPort  int
+	Cpu            int
+	MemoryLimitMiB int
+	DesiredCount   int
+	Environment    map[string]*string
+	Secrets        map[string]awsecs.Secret
+	// 空の場合はログを出力しない
+	LogRetention awslogs.RetentionDays
+	// true の場合はデプロイを CodeDeploy に任せる
+	BlueGreen bool
+	// タスクの起動後、ヘルスチェックの失敗を無視する時間（指定しない場合は ECS の既定値）
+	HealthCheckGracePeriod awscdk.Duration
+	// ローリングデプロイの設定（BlueGreen の場合は使わない）
+	RollingDeployment *RollingDeploymentProps
+	// 指定しない場合は DesiredCount のまま固定する
+	AutoScaling *AutoScalingProps
+}
+
+// AutoScalingProps はターゲット追跡とスケジュールによるタスク数の自動調整
+type AutoScalingProps struct {
+	MinCapacity int
+	MaxCapacity int
+	// 各メトリクスの目標値。0 の場合はそのメトリクスではスケールしない
+	CpuUtilization    int
+	MemoryUtilization int
+	// サービスのターゲットグループの、ターゲットあたりの1分間のリクエスト数
+	RequestsPerTarget int
+	ScaleInCooldown   awscdk.Duration
+	ScaleOutCooldown  awscdk.Duration
+	// 時間帯ごとの容量（例: 夜間は 0 にする）
+	Schedules []ScheduledCapacity
+}
+
+type ScheduledCapacity struct {
+	// スケジュールのコンストラクトID
+	Name string
+	// Application Auto Scaling の cron 式（例: "0 22 * * ? *"）
+	Cron string
+	// 例: Asia/Tokyo（空の場合は UTC）
+	TimeZone    string
+	MinCapacity int
+	MaxCapacity int
+}
+
+// RollingDeploymentProps は ECS のローリングデプロイの設定。
+// サーキットブレーカーを有効にし、失敗したデプロイやアラームが発生したデプロイを自動でロールバックする
+type RollingDeploymentProps struct {
+	// デプロイ中に維持するタスク数の下限と上限（DesiredCount に対する割合）
+	MinHealthyPercent int
+	MaxHealthyPercent int
+	// デプロイ中にサービスのターゲットグループがしきい値を超えたらロールバックする
+	Alarms monitoring.TargetGroupThresholds
+}
+
+// RoutingProps は1つの ALB の後ろに複数のサービスを置くときのリスナールール
+type RoutingProps struct {
+	Listener awselasticloadbalancingv2.IApplicationListener
+	// リスナー内で一意な優先度
+	Priority        int
+	PathPatterns    []string
+	HostHeaders     []string
+	HealthCheckPath string
+}
+
+type FargateWebService struct {
+	constructs.Construct
+	Repository    awsecr.IRepository
+	Cluster       awsecs.ICluster
+	TargetGroup   awselasticloadbalancingv2.ApplicationTargetGroup
+	TaskDef       awsecs.FargateTaskDefinition
+	Container     awsecs.ContainerDefinition
+	Service       awsecs.FargateService
+	ExecutionRole awsiam.IRole
+	TaskRole      awsiam.IRole
+	// ローリングデプロイのロールバックに使うアラーム
+	DeploymentAlarms []awscloudwatch.Alarm
+}
+
+func NewFargateWebService(scope constructs.Construct, id string, props *FargateWebServiceProps) *FargateWebService {
+	this := constructs.NewConstruct(scope, &id)
+	resourceName := props.ResourceName
+	containerName := props.ContainerName
+
+	repository := awsecr.Repository_FromRepositoryName(this, jsii.String("Repository"), jsii.String(props.RepositoryName))
+
+	cluster := props.Cluster
+	if cluster == nil {
+		clusterProps := &awsecs.ClusterProps{
+			ClusterName: jsii.String(resourceName + "-cluster"),
+			Vpc:         props.Vpc,
+		}
+		if props.ContainerInsights {
+			clusterProps.ContainerInsightsV2 = awsecs.ContainerInsights_ENABLED
+		}
+		cluster = awsecs.NewCluster(this, jsii.String("Cluster"), clusterProps)
+	}
+
+	taskRole := awsiam.NewRole(this, jsii.String("TaskRole"), &awsiam.RoleProps{
+		RoleName:  jsii.String(resourceName + "-task-role"),
+		AssumedBy: awsiam.NewServicePrincipal(jsii.String("ecs-tasks.amazonaws.com"), nil),
+	})
+
+	// イメージの取得、ログの出力、シークレットの読み取りの権限は、コンテナ定義から対象のリソースに限って付与される
+	executionRole := awsiam.NewRole(this, jsii.String("ExecutionRole"), &awsiam.RoleProps{
+		RoleName:  jsii.String(resourceName + "-execution-role"),
+		AssumedBy: awsiam.NewServicePrincipal(jsii.String("ecs-tasks.amazonaws.com"), nil),
+	})
+
+	taskDef := awsecs.NewFargateTaskDefinition(this, jsii.String("TaskDefinition"), &awsecs.FargateTaskDefinitionProps{
+		Family:         jsii.String(resourceName + "-taskdef"),
+		Cpu:            jsii.Number(props.Cpu),
+		MemoryLimitMiB: jsii.Number(props.MemoryLimitMiB),
+		TaskRole:       taskRole,
+		ExecutionRole:  executionRole,
+	})
+
+	var logging awsecs.LogDriver
+	if props.LogRetention != "" {
+		logging = awsecs.LogDrivers_AwsLogs(&awsecs.AwsLogDriverProps{
+			LogGroup: awslogs.NewLogGroup(this, jsii.String("LogGroup"), &awslogs.LogGroupProps{
+				LogGroupName:  jsii.String("/aws/ecs/" + resourceName + "-log-group"),
+				RemovalPolicy: awscdk.RemovalPolicy_DESTROY,
+				Retention:     props.LogRetention,
+			}),
+			StreamPrefix: jsii.String(resourceName + "-" + containerName),
+		})
+	}
+
+	container := taskDef.AddContainer(jsii.String(containerName), &awsecs.ContainerDefinitionOptions{
+		ContainerName:        jsii.String(containerName),
+		Image:                awsecs.ContainerImage_FromEcrRepository(repository, jsii.String("latest")),
+		Cpu:                  jsii.Number(props.Cpu),
+		MemoryReservationMiB: jsii.Number(props.MemoryLimitMiB),
+		Essential:            jsii.Bool(true),
+		Environment:          &props.Environment,
+		Secrets:              secrets(props.Secrets),
+		Logging:              logging,
+	})
+
+	container.AddPortMappings(&awsecs.PortMapping{
+		Name:          jsii.String(containerName),
+		ContainerPort: jsii.Number(props.ContainerPort),
+		HostPort:      jsii.Number(props.ContainerPort),
+		Protocol:      awsecs.Protocol_TCP,
+	})
+
+	targetGroup := props.TargetGroup
+	if props.Routing != nil {
+		targetGroup = newRoutedTargetGroup(this, props)
+	}
+
+	serviceProps := &awsecs.FargateServiceProps{
+		ServiceName:            jsii.String(resourceName + "-service"),
+		Cluster:                cluster,
+		TaskDefinition:         taskDef,
+		DesiredCount:           jsii.Number(props.DesiredCount),
+		AssignPublicIp:         jsii.Bool(false),
+		HealthCheckGracePeriod: props.HealthCheckGracePeriod,
+		SecurityGroups:         &[]awsec2.ISecurityGroup{props.SecurityGroup},
+		DeploymentController:   deploymentController(props.BlueGreen),
+	}
+	var alarms []awscloudwatch.Alarm
+	if rolling := props.RollingDeployment; rolling != nil && !props.BlueGreen {
+		alarms = monitoring.NewTargetGroupAlarms(this, resourceName, targetGroup, rolling.Alarms)
+		applyRollingDeployment(serviceProps, rolling, alarms)
+	}
+	service := awsecs.NewFargateService(this, jsii.String("Service"), serviceProps)
+
+	target := service.LoadBalancerTarget(&awsecs.LoadBalancerTargetOptions{
+		ContainerName: container.ContainerName(),
+		ContainerPort: jsii.Number(props.ContainerPort),
+	})
+
+	if props.Routing != nil {
+		// ALB からコンテナへの通信を許可する
+		service.Connections().AllowFrom(props.Routing.Listener, awsec2.Port_Tcp(jsii.Number(props.ContainerPort)), jsii.String("http from alb"))
+	}
+	targetGroup.AddTarget(target)
+
+	if props.AutoScaling != nil {
+		addAutoScaling(service, targetGroup, props.AutoScaling)
+	}
+
+	return &FargateWebService{
+		Construct:     this,
+		Repository:    repository,
+		Cluster:       cluster,
+		TargetGroup:   targetGroup,
+		TaskDef:       taskDef,
+		Container:     container,
+		Service:       service,
+		ExecutionRole: executionRole,
+		TaskRole:      taskRole,
+
+		DeploymentAlarms: alarms,
+	}
+}
+
+func applyRollingDeployment(serviceProps *awsecs.FargateServiceProps, rolling *RollingDeploymentProps, alarms []awscloudwatch.Alarm) {
+	serviceProps.MinHealthyPercent = jsii.Number(rolling.MinHealthyPercent)
+	serviceProps.MaxHealthyPercent = jsii.Number(rolling.MaxHealthyPercent)
+	// タスクが起動しない・ヘルスチェックに通らない場合は前のタスク定義に戻す
+	serviceProps.CircuitBreaker = &awsecs.DeploymentCircuitBreaker{
+		Enable:   jsii.Bool(true),
+		Rollback: jsii.Bool(true),
+	}
+
+	if len(alarms) == 0 {
+		return
+	}
+	alarmNames := make([]*string, 0, len(alarms))
+	for _, alarm := range alarms {
+		alarmNames = append(alarmNames, alarm.AlarmName())
+	}
+	serviceProps.DeploymentAlarms = &awsecs.DeploymentAlarmConfig{
+		AlarmNames: &alarmNames,
+		Behavior:   awsecs.AlarmBehavior_ROLLBACK_ON_ALARM,
+	}
+}
+
+func addAutoScaling(service awsecs.FargateService, targetGroup awselasticloadbalancingv2.ApplicationTargetGroup, props *AutoScalingProps) {
+	scaling := service.AutoScaleTaskCount(&awsapplicationautoscaling.EnableScalingProps{
+		MinCapacity: jsii.Number(props.MinCapacity),
+		MaxCapacity: jsii.Number(props.MaxCapacity),
+	})
+
+	if props.CpuUtilization > 0 {
+		scaling.ScaleOnCpuUtilization(jsii.String("CpuScaling"), &awsecs.CpuUtilizationScalingProps{
+			TargetUtilizationPercent: jsii.Number(props.CpuUtilization),
+			ScaleInCooldown:          props.ScaleInCooldown,
+			ScaleOutCooldown:         props.ScaleOutCooldown,
+		})
+	}
+	if props.MemoryUtilization > 0 {
+		scaling.ScaleOnMemoryUtilization(jsii.String("MemoryScaling"), &awsecs.MemoryUtilizationScalingProps{
+			TargetUtilizationPercent: jsii.Number(props.MemoryUtilization),
+			ScaleInCooldown:          props.ScaleInCooldown,
+			ScaleOutCooldown:         props.ScaleOutCooldown,
+		})
+	}
+	if props.RequestsPerTarget > 0 {
+		scaling.ScaleOnRequestCount(jsii.String("RequestCountScaling"), &awsecs.RequestCountScalingProps{
+			RequestsPerTarget: jsii.Number(props.RequestsPerTarget),
+			TargetGroup:       targetGroup,
+			ScaleInCooldown:   props.ScaleInCooldown,
+			ScaleOutCooldown:  props.ScaleOutCooldown,
+		})
+	}
+
+	for _, schedule := range props.Schedules {
+		var timeZone awscdk.TimeZone
+		if schedule.TimeZone != "" {
+			timeZone = awscdk.TimeZone_Of(jsii.String(schedule.TimeZone))
+		}
+		scaling.ScaleOnSchedule(jsii.String(schedule.Name), &awsapplicationautoscaling.ScalingSchedule{
+			Schedule:    awsapplicationautoscaling.Schedule_Expression(jsii.String("cron(" + schedule.Cron + ")")),
+			TimeZone:    timeZone,
+			MinCapacity: jsii.Number(schedule.MinCapacity),
+			MaxCapacity: jsii.Number(schedule.MaxCapacity),
+		})
+	}
+}
+
+// サービス用のターゲットグループを作成し、リスナールールで振り分ける
+func newRoutedTargetGroup(scope constructs.Construct, props *FargateWebServiceProps) awselasticloadbalancingv2.ApplicationTargetGroup {
+	routing := props.Routing
+
+	targetGroup := awselasticloadbalancingv2.NewApplicationTargetGroup(scope, jsii.String("TargetGroup"), &awselasticloadbalancingv2.ApplicationTargetGroupProps{
+		TargetGroupName: jsii.String(props.ResourceName + "-tg"),
+		Vpc:             props.Vpc,
+		Port:            jsii.Number(props.ContainerPort),
+		Protocol:        awselasticloadbalancingv2.ApplicationProtocol_HTTP,
+		TargetType:      awselasticloadbalancingv2.TargetType_IP,
+		HealthCheck: &awselasticloadbalancingv2.HealthCheck{
+			Path:     jsii.String(routing.HealthCheckPath),
+			Interval: awscdk.Duration_Seconds(jsii.Number(60)),
+			Timeout:  awscdk.Duration_Seconds(jsii.Number(30)),
+		},
+	})
+
+	var conditions []awselasticloadbalancingv2.ListenerCondition
+	if len(routing.PathPatterns) > 0 {
+		conditions = append(conditions, awselasticloadbalancingv2.ListenerCondition_PathPatterns(jsii.Strings(routing.PathPatterns...)))
+	}
+	if len(routing.HostHeaders) > 0 {
+		conditions = append(conditions, awselasticloadbalancingv2.ListenerCondition_HostHeaders(jsii.Strings(routing.HostHeaders...)))
+	}
+
+	awselasticloadbalancingv2.NewApplicationListenerRule(scope, jsii.String("ListenerRule"), &awselasticloadbalancingv2.ApplicationListenerRuleProps{
+		Listener:     routing.Listener,
+		Priority:     jsii.Number(routing.Priority),
+		Conditions:   &conditions,
+		TargetGroups: &[]awselasticloadbalancingv2.IApplicationTargetGroup{targetGroup},
+	})
+
+	return targetGroup
+}
+
+// Secrets を指定しない場合はコンテナ定義に含めない
+func secrets(values map[string]awsecs.Secret) *map[string]awsecs.Secret {
+	if len(values) == 0 {
+		return nil
+	}
+	return &values
+}
+
+// ブルーグリーンデプロイの場合は CodeDeploy にデプロイを任せる
+func deploymentController(blueGreen bool) *awsecs.DeploymentController {
+	if blueGreen {
+		return &awsecs.DeploymentController{
+			Type: awsecs.DeploymentControllerType_CODE_DEPLOY,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package service_test
+
+import (
+	"testing"
+
+	"iaclib/network"
+	"iaclib/service"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	"github.com/aws/jsii-runtime-go"
+)
+
+func TestTwoServicesBehindOneAlb(t *testing.T) {
+	// GIVEN
+	app := awscdk.NewApp(nil)
+	stack := awscdk.NewStack(app, jsii.String("test"), nil)
+
+	network := network.NewAppNetwork(stack, "Network", &network.AppNetworkProps{
+		ResourceName:    "app",
+		ContainerPort:   80,
+		HealthCheckPath: "/",
+	})
+
+	// WHEN
+	web := service.NewFargateWebService(stack, "Web", &service.FargateWebServiceProps{
+		ResourceName:   "app-web",
+		Vpc:            network.Vpc,
+		SecurityGroup:  network.EcsSecurityGroup,
+		TargetGroup:    network.TargetGroup1,
+		RepositoryName: "web",
+		ContainerName:  "web",
+		ContainerPort:  80,
+		Cpu:            256,
+		MemoryLimitMiB: 512,
+		DesiredCount:   1,
+	})
+	service.NewFargateWebService(stack, "Admin", &service.FargateWebServiceProps{
+		ResourceName:  "app-admin",
+		Vpc:           network.Vpc,
+		SecurityGroup: network.EcsSecurityGroup,
+		Cluster:       web.Cluster,
+		Routing: &service.RoutingProps{
+			Listener:        network.ProdListener,
+			Priority:        10,
+			PathPatterns:    []string{"/admin/*"},
+			HealthCheckPath: "/admin/up",
+		},
+		RepositoryName: "admin",
+		ContainerName:  "admin",
+		ContainerPort:  8000,
+		Cpu:            256,
+		MemoryLimitMiB: 512,
+		DesiredCount:   1,
+	})
+
+	// THEN
+	template := assertions.Template_FromStack(stack, nil)
+	template.ResourceCountIs(jsii.String("AWS::ECS::Cluster"), jsii.Number(1))
+	template.ResourceCountIs(jsii.String("AWS::ECS::Service"), jsii.Number(2))
+	template.ResourceCountIs(jsii.String("AWS::ElasticLoadBalancingV2::LoadBalancer"), jsii.Number(1))
+	template.HasResourceProperties(jsii.String("AWS::ElasticLoadBalancingV2::TargetGroup"), map[string]interface{}{
+		"Name":            "app-admin-tg",
+		"Port":            8000,
+		"HealthCheckPath": "/admin/up",
+	})
+	template.HasResourceProperties(jsii.String("AWS::ElasticLoadBalancingV2::ListenerRule"), map[string]interface{}{
+		"Priority": 10,
+		"Conditions": []interface{}{
+			map[string]interface{}{
+				"Field":             "path-pattern",
+				"PathPatternConfig": map[string]interface{}{"Values": []interface{}{"/admin/*"}},
+			},
+		},
+	})
+	// 2つ目のサービスのポートも ALB から許可される
+	template.HasResourceProperties(jsii.String("AWS::EC2::SecurityGroupIngress"), map[string]interface{}{
+		"FromPort": 8000,
+		"ToPort":   8000,
+	})
+}
@@ -0,0 +1,83 @@
+// Package snapshottest は合成したテンプレートを testdata のスナップショットと比較するテスト用のヘルパー。
+// 各アプリのテストから使うため internal にはしない
+package snapshottest
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// スナップショットを更新する場合は `go test . -run Snapshot -update` を実行する
+var update = flag.Bool("update", false, "update snapshot files in testdata")
+
+// アセットのハッシュはソースの変更で毎回変わるため固定値に置き換える
+var assetHashPattern = regexp.MustCompile(`[0-9a-f]{64}`)
+
+// Assert は template を testdata/<name> と比較し、-update の場合はファイルを書き換える
+func Assert(t *testing.T, name string, template *map[string]interface{}) {
+	t.Helper()
+
+	b, err := json.MarshalIndent(template, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := assetHashPattern.ReplaceAllString(string(b), "[ASSET_HASH]") + "\n"
+
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read snapshot (run `go test . -run Snapshot -update` to create it): %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("template does not match %s (run `go test . -run Snapshot -update` if the change is intended):\n%s", path, diffLines(string(want), got))
+	}
+}
+
+// 最初に食い違った行の前後を unified 形式に近い形で返す
+func diffLines(want, got string) string {
+	wantLines := strings.Split(want, "\n")
+	gotLines := strings.Split(got, "\n")
+
+	first := 0
+	for first < len(wantLines) && first < len(gotLines) && wantLines[first] == gotLines[first] {
+		first++
+	}
+	wantEnd, gotEnd := len(wantLines), len(gotLines)
+	for wantEnd > first && gotEnd > first && wantLines[wantEnd-1] == gotLines[gotEnd-1] {
+		wantEnd--
+		gotEnd--
+	}
+
+	const context = 3
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "@@ line %d @@\n", first+1)
+	for i := max(0, first-context); i < first; i++ {
+		fmt.Fprintf(&sb, "  %s\n", wantLines[i])
+	}
+	for i := first; i < wantEnd; i++ {
+		fmt.Fprintf(&sb, "- %s\n", wantLines[i])
+	}
+	for i := first; i < gotEnd; i++ {
+		fmt.Fprintf(&sb, "+ %s\n", gotLines[i])
+	}
+	for i := wantEnd; i < min(len(wantLines), wantEnd+context); i++ {
+		fmt.Fprintf(&sb, "  %s\n", wantLines[i])
+	}
+	return sb.String()
+}
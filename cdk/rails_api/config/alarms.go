@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+
+	"iaclib/envconfig"
+)
+
+// Alarms は運用中に SNS で通知するアラームのしきい値（0 の項目はアラームを作らない）
+type Alarms struct {
+	// 通知先のメールアドレス
+	Emails []string
+	// ALB とターゲットの 5xx 応答の割合（%）
+	Max5xxRatePercent int
+	// ターゲットの応答時間の p99（ミリ秒）
+	MaxResponseTimeMillis   int
+	MaxServiceCpuPercent    int
+	MaxServiceMemoryPercent int
+	MaxDBCpuPercent         int
+	MaxDBConnections        int
+	// RDS の空きストレージの下限（MiB）
+	MinDBFreeStorageMiB     int
+	MaxDBReadLatencyMillis  int
+	MaxDBWriteLatencyMillis int
+}
+
+// ステージごとの既定値。接続数は DB インスタンスの max_connections のおよそ 8 割
+var defaultAlarms = map[Stage]Alarms{
+	StageDev: {
+		Max5xxRatePercent:       10,
+		MaxResponseTimeMillis:   3000,
+		MaxServiceCpuPercent:    90,
+		MaxServiceMemoryPercent: 90,
+		MaxDBCpuPercent:         90,
+		MaxDBConnections:        70,
+		MinDBFreeStorageMiB:     2048,
+		MaxDBReadLatencyMillis:  50,
+		MaxDBWriteLatencyMillis: 50,
+	},
+	StageStaging: {
+		Max5xxRatePercent:       5,
+		MaxResponseTimeMillis:   2000,
+		MaxServiceCpuPercent:    85,
+		MaxServiceMemoryPercent: 85,
+		MaxDBCpuPercent:         85,
+		MaxDBConnections:        180,
+		MinDBFreeStorageMiB:     2048,
+		MaxDBReadLatencyMillis:  20,
+		MaxDBWriteLatencyMillis: 20,
+	},
+	StageProd: {
+		Max5xxRatePercent:       1,
+		MaxResponseTimeMillis:   1000,
+		MaxServiceCpuPercent:    80,
+		MaxServiceMemoryPercent: 80,
+		MaxDBCpuPercent:         80,
+		MaxDBConnections:        360,
+		MinDBFreeStorageMiB:     5120,
+		MaxDBReadLatencyMillis:  20,
+		MaxDBWriteLatencyMillis: 20,
+	},
+}
+
+func (a Alarms) problems() []error {
+	var errs []error
+
+	errs = append(errs, envconfig.CheckEmails("ALARM_EMAILS", a.Emails)...)
+	if a.Max5xxRatePercent < 0 || a.Max5xxRatePercent > 100 {
+		errs = append(errs, fmt.Errorf("ALARM_5XX_RATE must be between 0 (disabled) and 100, got %d", a.Max5xxRatePercent))
+	}
+	for _, percent := range []struct {
+		name  string
+		value int
+	}{
+		{"ALARM_ECS_CPU", a.MaxServiceCpuPercent},
+		{"ALARM_ECS_MEMORY", a.MaxServiceMemoryPercent},
+		{"ALARM_DB_CPU", a.MaxDBCpuPercent},
+	} {
+		if percent.value < 0 || percent.value > 100 {
+			errs = append(errs, fmt.Errorf("%s must be between 0 (disabled) and 100, got %d", percent.name, percent.value))
+		}
+	}
+	for _, value := range []struct {
+		name  string
+		value int
+	}{
+		{"ALARM_RESPONSE_TIME", a.MaxResponseTimeMillis},
+		{"ALARM_DB_CONNECTIONS", a.MaxDBConnections},
+		{"ALARM_DB_FREE_STORAGE", a.MinDBFreeStorageMiB},
+		{"ALARM_DB_READ_LATENCY", a.MaxDBReadLatencyMillis},
+		{"ALARM_DB_WRITE_LATENCY", a.MaxDBWriteLatencyMillis},
+	} {
+		if value.value < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative, got %d", value.name, value.value))
+		}
+	}
+
+	return errs
+}
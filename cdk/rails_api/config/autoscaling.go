@@ -0,0 +1,115 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AutoScaling は ECS サービスのタスク数の自動調整
+type AutoScaling struct {
+	MinCapacity int
+	MaxCapacity int
+	// ターゲット追跡の目標値（0 の場合はそのメトリクスではスケールしない）
+	CpuTarget         int
+	MemoryTarget      int
+	RequestsPerTarget int
+	// スケールイン / スケールアウト後に次の調整までに待つ秒数
+	ScaleInCooldownSeconds  int
+	ScaleOutCooldownSeconds int
+	// ScaleToZeroCron でタスク数を 0 にし、ScaleUpCron で MinCapacity / MaxCapacity に戻す（空の場合は無効）
+	ScaleToZeroCron  string
+	ScaleUpCron      string
+	ScheduleTimeZone string
+}
+
+// ステージごとの既定値。MinCapacity は DESIRED_COUNT に合わせる
+var defaultAutoScaling = map[Stage]AutoScaling{
+	StageDev: {
+		MaxCapacity:             2,
+		CpuTarget:               60,
+		MemoryTarget:            75,
+		RequestsPerTarget:       1000,
+		ScaleInCooldownSeconds:  300,
+		ScaleOutCooldownSeconds: 60,
+		// 平日の日中以外は停止する
+		ScaleToZeroCron:  "0 22 * * ? *",
+		ScaleUpCron:      "0 8 ? * MON-FRI *",
+		ScheduleTimeZone: "Asia/Tokyo",
+	},
+	StageStaging: {
+		MaxCapacity:             4,
+		CpuTarget:               60,
+		MemoryTarget:            75,
+		RequestsPerTarget:       1000,
+		ScaleInCooldownSeconds:  300,
+		ScaleOutCooldownSeconds: 60,
+		ScheduleTimeZone:        "Asia/Tokyo",
+	},
+	StageProd: {
+		MaxCapacity:             10,
+		CpuTarget:               60,
+		MemoryTarget:            75,
+		RequestsPerTarget:       1000,
+		ScaleInCooldownSeconds:  300,
+		ScaleOutCooldownSeconds: 60,
+		ScheduleTimeZone:        "Asia/Tokyo",
+	},
+}
+
+// Scheduled はスケジュールによるスケーリングが有効か
+func (a AutoScaling) Scheduled() bool {
+	return a.ScaleToZeroCron != "" && a.ScaleUpCron != ""
+}
+
+func (a AutoScaling) problems(desiredCount int) []error {
+	var errs []error
+
+	if a.MinCapacity < 0 {
+		errs = append(errs, fmt.Errorf("AUTOSCALING_MIN_CAPACITY must not be negative, got %d", a.MinCapacity))
+	}
+	if a.MaxCapacity < 1 || a.MaxCapacity < a.MinCapacity {
+		errs = append(errs, fmt.Errorf("AUTOSCALING_MAX_CAPACITY must be at least 1 and AUTOSCALING_MIN_CAPACITY (%d), got %d", a.MinCapacity, a.MaxCapacity))
+	}
+	if desiredCount < a.MinCapacity || desiredCount > a.MaxCapacity {
+		errs = append(errs, fmt.Errorf("DESIRED_COUNT must be between AUTOSCALING_MIN_CAPACITY (%d) and AUTOSCALING_MAX_CAPACITY (%d), got %d", a.MinCapacity, a.MaxCapacity, desiredCount))
+	}
+
+	for _, target := range []struct {
+		name  string
+		value int
+	}{
+		{"AUTOSCALING_CPU_TARGET", a.CpuTarget},
+		{"AUTOSCALING_MEMORY_TARGET", a.MemoryTarget},
+	} {
+		if target.value < 0 || target.value > 100 {
+			errs = append(errs, fmt.Errorf("%s must be between 0 (disabled) and 100, got %d", target.name, target.value))
+		}
+	}
+	if a.RequestsPerTarget < 0 {
+		errs = append(errs, fmt.Errorf("AUTOSCALING_REQUESTS_PER_TARGET must not be negative, got %d", a.RequestsPerTarget))
+	}
+	if a.CpuTarget == 0 && a.MemoryTarget == 0 && a.RequestsPerTarget == 0 && a.MinCapacity != a.MaxCapacity {
+		errs = append(errs, fmt.Errorf("at least one of AUTOSCALING_CPU_TARGET, AUTOSCALING_MEMORY_TARGET and AUTOSCALING_REQUESTS_PER_TARGET is required when AUTOSCALING_MIN_CAPACITY and AUTOSCALING_MAX_CAPACITY differ"))
+	}
+	if a.ScaleInCooldownSeconds < 0 || a.ScaleOutCooldownSeconds < 0 {
+		errs = append(errs, fmt.Errorf("AUTOSCALING_SCALE_IN_COOLDOWN and AUTOSCALING_SCALE_OUT_COOLDOWN must not be negative"))
+	}
+
+	if (a.ScaleToZeroCron == "") != (a.ScaleUpCron == "") {
+		errs = append(errs, fmt.Errorf("SCALE_TO_ZERO_CRON and SCALE_UP_CRON must be set together"))
+	}
+	for _, cron := range []struct {
+		name  string
+		value string
+	}{
+		{"SCALE_TO_ZERO_CRON", a.ScaleToZeroCron},
+		{"SCALE_UP_CRON", a.ScaleUpCron},
+	} {
+		// Application Auto Scaling の cron 式は「分 時 日 月 曜日 年」の6項目
+		if cron.value != "" && len(strings.Fields(cron.value)) != 6 {
+			errs = append(errs, fmt.Errorf("%s must have 6 fields such as \"0 22 * * ? *\", got %q", cron.name, cron.value))
+		}
+	}
+
+	return errs
+}
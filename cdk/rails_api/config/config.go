@@ -0,0 +1,281 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"iaclib/database"
+	"iaclib/deployment"
+	"iaclib/envconfig"
+)
+
+var domainPattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63}$`)
+
+// イメージの取得、ログ出力、DB 認証情報の注入に必要なエンドポイント
+var requiredInterfaceEndpoints = []string{"ecr.api", "ecr.dkr", "logs", "secretsmanager"}
+
+// DeploymentMode は ECS サービスのデプロイ方式
+type DeploymentMode string
+
+const (
+	// ECS のローリングアップデート（既定）
+	DeploymentModeRolling DeploymentMode = "rolling"
+	// CodeDeploy によるブルーグリーンデプロイ
+	DeploymentModeBlueGreen DeploymentMode = "bluegreen"
+)
+
+// Config はスタック全体で使う設定値
+type Config struct {
+	Stage          Stage
+	AccountID      string
+	Region         string
+	ResourceName   string
+	RepositoryName string
+	RailsMasterKey string
+	DomainName     string
+	HostedZoneName string
+	DBUsername     string
+	DBRotationDays int
+	// RDS のエンジン（単一のインスタンスまたは Aurora）
+	DBEngine DatabaseEngine
+	// RDS Proxy
+	DBProxy DatabaseProxy
+	// RDS の可用性と削除時の保護
+	DBProfile DatabaseProfile
+	// RDS の Performance Insights、拡張モニタリング、ログのエクスポート
+	DBMonitoring  DatabaseMonitoring
+	AllowedOrigin string
+	// NAT ゲートウェイを使わないため、プライベートサブネットから利用する AWS サービスはエンドポイント経由で接続する
+	InterfaceEndpoints []string
+	DeploymentMode     DeploymentMode
+	RollingDeployment  RollingDeployment
+	BlueGreen          BlueGreenDeployment
+	DeploymentAlarms   DeploymentAlarms
+	// 運用中のアラームのしきい値と通知先
+	Alarms Alarms
+	// タスクの起動後、ALB のヘルスチェックの失敗を無視する秒数
+	HealthCheckGracePeriodSeconds int
+	// ブルーグリーンデプロイのテストリスナーのポートと、アクセスを許可する CIDR
+	TestListenerPort int
+	OfficeCidrs      []string
+	Sizing           Sizing
+	AutoScaling      AutoScaling
+}
+
+// Load は指定したステージの設定を .env、.env.<stage> とプロセスの環境変数から読み込み、検証する
+//
+// 値は次の順で探す:
+//  1. 環境変数 <STAGE>_<KEY>（例: PROD_DOMAIN_NAME）
+//  2. .env.<stage>
+//  3. 環境変数 <KEY>
+//  4. .env
+//
+// RESOURCE_NAME にはステージ名が付与される（例: rails-api → rails-api-prod）
+func Load(stage Stage) (*Config, error) {
+	if !stage.valid() {
+		return nil, fmt.Errorf("unknown stage %q (expected one of %v)", stage, Stages)
+	}
+
+	src, err := newSource(stage)
+	if err != nil {
+		return nil, err
+	}
+
+	sizing := defaultSizing[stage]
+	autoScaling := defaultAutoScaling[stage]
+	blueGreen := defaultBlueGreenDeployment[stage]
+	alarms := defaultAlarms[stage]
+	dbProfile := defaultDatabaseProfile[stage]
+	dbMonitoring := defaultDatabaseMonitoring[stage]
+	desiredCount := src.Int("DESIRED_COUNT", sizing.DesiredCount)
+	cfg := &Config{
+		Stage:          stage,
+		AccountID:      src.Get("ACCOUNT_ID"),
+		Region:         src.Get("REGION"),
+		ResourceName:   src.Get("RESOURCE_NAME"),
+		RepositoryName: src.Get("REPOSITORY_NAME"),
+		RailsMasterKey: src.Get("RAILS_MASTER_KEY"),
+		DomainName:     src.Get("DOMAIN_NAME"),
+		HostedZoneName: src.Get("HOSTED_ZONE_NAME"),
+		DBUsername:     src.Get("DB_USERNAME"),
+		DBRotationDays: src.Int("DB_ROTATION_DAYS", 0),
+		DBEngine: DatabaseEngine{
+			Engine:                database.Engine(src.String("DB_ENGINE", string(defaultDatabaseEngine.Engine))),
+			AuroraReaders:         src.Int("DB_AURORA_READERS", defaultDatabaseEngine.AuroraReaders),
+			ServerlessMinCapacity: src.Float("DB_SERVERLESS_MIN_ACU", defaultDatabaseEngine.ServerlessMinCapacity),
+			ServerlessMaxCapacity: src.Float("DB_SERVERLESS_MAX_ACU", defaultDatabaseEngine.ServerlessMaxCapacity),
+		},
+		DBProxy: DatabaseProxy{
+			Enabled:               src.Bool("DB_PROXY", false),
+			MaxConnectionsPercent: src.Int("DB_PROXY_MAX_CONNECTIONS_PERCENT", 0),
+		},
+		DBProfile: DatabaseProfile{
+			Profile:           database.Profile(src.String("DB_PROFILE", string(dbProfile.Profile))),
+			BackupWindow:      src.Optional("DB_BACKUP_WINDOW", dbProfile.BackupWindow),
+			MaintenanceWindow: src.Optional("DB_MAINTENANCE_WINDOW", dbProfile.MaintenanceWindow),
+			Identifier:        src.Get("DB_IDENTIFIER"),
+		},
+		DBMonitoring: DatabaseMonitoring{
+			Level:                     database.MonitoringLevel(src.String("DB_MONITORING", string(dbMonitoring.Level))),
+			PerformanceInsightsMonths: src.Int("DB_PERFORMANCE_INSIGHTS_MONTHS", dbMonitoring.PerformanceInsightsMonths),
+			IntervalSeconds:           src.Int("DB_MONITORING_INTERVAL", dbMonitoring.IntervalSeconds),
+			SlowQueryMillis:           src.Int("DB_SLOW_QUERY_MILLIS", dbMonitoring.SlowQueryMillis),
+		},
+		AllowedOrigin:      src.Get("ALLOWED_ORIGIN"),
+		InterfaceEndpoints: src.List("VPC_INTERFACE_ENDPOINTS", requiredInterfaceEndpoints),
+		DeploymentMode:     DeploymentMode(src.String("DEPLOYMENT_MODE", string(DeploymentModeRolling))),
+		RollingDeployment: RollingDeployment{
+			MinHealthyPercent: src.Int("DEPLOYMENT_MIN_HEALTHY_PERCENT", defaultRollingDeployment.MinHealthyPercent),
+			MaxHealthyPercent: src.Int("DEPLOYMENT_MAX_HEALTHY_PERCENT", defaultRollingDeployment.MaxHealthyPercent),
+		},
+		BlueGreen: BlueGreenDeployment{
+			TrafficShifting:                deployment.TrafficShiftingType(src.String("TRAFFIC_SHIFTING", string(blueGreen.TrafficShifting))),
+			TrafficShiftingPercent:         src.Int("TRAFFIC_SHIFTING_PERCENT", blueGreen.TrafficShiftingPercent),
+			TrafficShiftingIntervalMinutes: src.Int("TRAFFIC_SHIFTING_INTERVAL", blueGreen.TrafficShiftingIntervalMinutes),
+			DeploymentConfigName:           src.Get("CODEDEPLOY_DEPLOYMENT_CONFIG"),
+			TerminationWaitMinutes:         src.Int("BLUE_TERMINATION_WAIT", blueGreen.TerminationWaitMinutes),
+		},
+		DeploymentAlarms: DeploymentAlarms{
+			Max5xxRatePercent:     src.Int("DEPLOYMENT_ALARM_5XX_RATE", defaultDeploymentAlarms.Max5xxRatePercent),
+			MaxResponseTimeMillis: src.Int("DEPLOYMENT_ALARM_RESPONSE_TIME", defaultDeploymentAlarms.MaxResponseTimeMillis),
+			MaxUnhealthyHosts:     src.Int("DEPLOYMENT_ALARM_UNHEALTHY_HOSTS", defaultDeploymentAlarms.MaxUnhealthyHosts),
+		},
+		Alarms: Alarms{
+			Emails:                  src.List("ALARM_EMAILS", nil),
+			Max5xxRatePercent:       src.Int("ALARM_5XX_RATE", alarms.Max5xxRatePercent),
+			MaxResponseTimeMillis:   src.Int("ALARM_RESPONSE_TIME", alarms.MaxResponseTimeMillis),
+			MaxServiceCpuPercent:    src.Int("ALARM_ECS_CPU", alarms.MaxServiceCpuPercent),
+			MaxServiceMemoryPercent: src.Int("ALARM_ECS_MEMORY", alarms.MaxServiceMemoryPercent),
+			MaxDBCpuPercent:         src.Int("ALARM_DB_CPU", alarms.MaxDBCpuPercent),
+			MaxDBConnections:        src.Int("ALARM_DB_CONNECTIONS", alarms.MaxDBConnections),
+			MinDBFreeStorageMiB:     src.Int("ALARM_DB_FREE_STORAGE", alarms.MinDBFreeStorageMiB),
+			MaxDBReadLatencyMillis:  src.Int("ALARM_DB_READ_LATENCY", alarms.MaxDBReadLatencyMillis),
+			MaxDBWriteLatencyMillis: src.Int("ALARM_DB_WRITE_LATENCY", alarms.MaxDBWriteLatencyMillis),
+		},
+		HealthCheckGracePeriodSeconds: src.Int("HEALTH_CHECK_GRACE_PERIOD", 120),
+		TestListenerPort:              src.Int("TEST_LISTENER_PORT", 8443),
+		OfficeCidrs:                   src.List("OFFICE_CIDRS", nil),
+		Sizing: Sizing{
+			Cpu:                   src.Int("FARGATE_CPU", sizing.Cpu),
+			MemoryLimitMiB:        src.Int("FARGATE_MEMORY", sizing.MemoryLimitMiB),
+			DesiredCount:          desiredCount,
+			LogRetentionDays:      src.Int("LOG_RETENTION_DAYS", sizing.LogRetentionDays),
+			DBInstanceType:        src.String("DB_INSTANCE_TYPE", sizing.DBInstanceType),
+			DBMultiAz:             src.Bool("DB_MULTI_AZ", sizing.DBMultiAz),
+			DBBackupRetentionDays: src.Int("DB_BACKUP_RETENTION_DAYS", sizing.DBBackupRetentionDays),
+		},
+		AutoScaling: AutoScaling{
+			MinCapacity:             src.Int("AUTOSCALING_MIN_CAPACITY", desiredCount),
+			MaxCapacity:             src.Int("AUTOSCALING_MAX_CAPACITY", autoScaling.MaxCapacity),
+			CpuTarget:               src.Int("AUTOSCALING_CPU_TARGET", autoScaling.CpuTarget),
+			MemoryTarget:            src.Int("AUTOSCALING_MEMORY_TARGET", autoScaling.MemoryTarget),
+			RequestsPerTarget:       src.Int("AUTOSCALING_REQUESTS_PER_TARGET", autoScaling.RequestsPerTarget),
+			ScaleInCooldownSeconds:  src.Int("AUTOSCALING_SCALE_IN_COOLDOWN", autoScaling.ScaleInCooldownSeconds),
+			ScaleOutCooldownSeconds: src.Int("AUTOSCALING_SCALE_OUT_COOLDOWN", autoScaling.ScaleOutCooldownSeconds),
+			ScaleToZeroCron:         src.Optional("SCALE_TO_ZERO_CRON", autoScaling.ScaleToZeroCron),
+			ScaleUpCron:             src.Optional("SCALE_UP_CRON", autoScaling.ScaleUpCron),
+			ScheduleTimeZone:        src.String("SCHEDULE_TIME_ZONE", autoScaling.ScheduleTimeZone),
+		},
+	}
+	if cfg.ResourceName != "" {
+		cfg.ResourceName += "-" + string(stage)
+	}
+	if cfg.HostedZoneName == "" {
+		cfg.HostedZoneName = cfg.DomainName
+	}
+
+	if err := envconfig.ValidationError(append(src.Errors(), cfg.problems()...)); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Validate は必須項目と各値の形式をまとめて検証する
+func (c *Config) Validate() error {
+	return envconfig.ValidationError(c.problems())
+}
+
+func (c *Config) problems() []error {
+	var errs []error
+
+	errs = append(errs, envconfig.Required(
+		envconfig.Field{Name: "ACCOUNT_ID", Value: c.AccountID},
+		envconfig.Field{Name: "REGION", Value: c.Region},
+		envconfig.Field{Name: "RESOURCE_NAME", Value: c.ResourceName},
+		envconfig.Field{Name: "REPOSITORY_NAME", Value: c.RepositoryName},
+		envconfig.Field{Name: "RAILS_MASTER_KEY", Value: c.RailsMasterKey},
+		envconfig.Field{Name: "DOMAIN_NAME", Value: c.DomainName},
+		envconfig.Field{Name: "DB_USERNAME", Value: c.DBUsername},
+		envconfig.Field{Name: "ALLOWED_ORIGIN", Value: c.AllowedOrigin},
+	)...)
+	errs = append(errs, envconfig.CheckAccountID(c.AccountID)...)
+	errs = append(errs, envconfig.CheckRegion(c.Region)...)
+	errs = append(errs, envconfig.CheckResourceName(c.ResourceName)...)
+	if c.DomainName != "" && !domainPattern.MatchString(c.DomainName) {
+		errs = append(errs, fmt.Errorf("DOMAIN_NAME must be a lower-case domain name, got %q", c.DomainName))
+	}
+	if c.HostedZoneName != "" {
+		if !domainPattern.MatchString(c.HostedZoneName) {
+			errs = append(errs, fmt.Errorf("HOSTED_ZONE_NAME must be a lower-case domain name, got %q", c.HostedZoneName))
+		} else if c.DomainName != c.HostedZoneName && !strings.HasSuffix(c.DomainName, "."+c.HostedZoneName) {
+			errs = append(errs, fmt.Errorf("DOMAIN_NAME %q is not inside HOSTED_ZONE_NAME %q", c.DomainName, c.HostedZoneName))
+		}
+	}
+	if c.DBRotationDays < 0 || c.DBRotationDays > 1000 {
+		errs = append(errs, fmt.Errorf("DB_ROTATION_DAYS must be between 0 (disabled) and 1000, got %d", c.DBRotationDays))
+	}
+
+	errs = append(errs, envconfig.CheckInterfaceEndpoints(c.InterfaceEndpoints, requiredInterfaceEndpoints)...)
+
+	if c.HealthCheckGracePeriodSeconds < 0 {
+		errs = append(errs, fmt.Errorf("HEALTH_CHECK_GRACE_PERIOD must not be negative, got %d", c.HealthCheckGracePeriodSeconds))
+	}
+
+	switch c.DeploymentMode {
+	case DeploymentModeRolling:
+		errs = append(errs, c.RollingDeployment.problems()...)
+	case DeploymentModeBlueGreen:
+		errs = append(errs, c.BlueGreen.problems()...)
+		if c.TestListenerPort < 1 || c.TestListenerPort > 65535 || c.TestListenerPort == 80 || c.TestListenerPort == 443 {
+			errs = append(errs, fmt.Errorf("TEST_LISTENER_PORT must be a port other than 80 and 443, got %d", c.TestListenerPort))
+		}
+		if len(c.OfficeCidrs) == 0 {
+			errs = append(errs, fmt.Errorf("OFFICE_CIDRS is required when DEPLOYMENT_MODE is %s", DeploymentModeBlueGreen))
+		}
+		for _, cidr := range c.OfficeCidrs {
+			// ALB は IPv4 のみで受け付けている
+			if ip, _, err := net.ParseCIDR(cidr); err != nil || ip.To4() == nil {
+				errs = append(errs, fmt.Errorf("OFFICE_CIDRS must contain IPv4 CIDR blocks such as 203.0.113.0/24, got %q", cidr))
+			}
+		}
+	default:
+		errs = append(errs, fmt.Errorf("DEPLOYMENT_MODE must be %s or %s, got %q", DeploymentModeRolling, DeploymentModeBlueGreen, c.DeploymentMode))
+	}
+
+	errs = append(errs, c.DBEngine.problems(c.Sizing.DBInstanceType)...)
+	errs = append(errs, c.DBProxy.problems()...)
+	errs = append(errs, c.DBProfile.problems(c.Sizing.DBMultiAz)...)
+	errs = append(errs, c.DBMonitoring.problems()...)
+	errs = append(errs, c.DeploymentAlarms.problems()...)
+	errs = append(errs, c.Alarms.problems()...)
+	errs = append(errs, c.Sizing.problems()...)
+	errs = append(errs, c.AutoScaling.problems(c.Sizing.DesiredCount)...)
+
+	return errs
+}
+
+// Load に書いた順で値を探す
+func newSource(stage Stage) (*envconfig.Source, error) {
+	stageFile, err := envconfig.File(".env." + string(stage))
+	if err != nil {
+		return nil, err
+	}
+	baseFile, err := envconfig.File(".env")
+	if err != nil {
+		return nil, err
+	}
+	return envconfig.NewSource(envconfig.Env(strings.ToUpper(string(stage))+"_"), stageFile, envconfig.Env(""), baseFile), nil
+}
@@ -0,0 +1,213 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+
+	"iaclib/database"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsrds"
+)
+
+var (
+	backupWindowPattern      = regexp.MustCompile(`^([01]\d|2[0-3]):[0-5]\d-([01]\d|2[0-3]):[0-5]\d$`)
+	dbIdentifierPattern      = regexp.MustCompile(`^[a-z](-?[a-z0-9])*$`)
+	maintenanceWindowPattern = regexp.MustCompile(`^(mon|tue|wed|thu|fri|sat|sun):([01]\d|2[0-3]):[0-5]\d-(mon|tue|wed|thu|fri|sat|sun):([01]\d|2[0-3]):[0-5]\d$`)
+)
+
+// DatabaseEngine は RDS のエンジンと Aurora の構成
+type DatabaseEngine struct {
+	// postgres（単一のインスタンス）または aurora-postgresql
+	Engine database.Engine
+	// Aurora のリーダーインスタンスの数
+	AuroraReaders int
+	// Aurora Serverless v2 の最小 / 最大 ACU（最大が 0 の場合は DB_INSTANCE_TYPE のプロビジョンドインスタンス）
+	ServerlessMinCapacity float64
+	ServerlessMaxCapacity float64
+}
+
+var defaultDatabaseEngine = DatabaseEngine{
+	Engine: database.EnginePostgres,
+}
+
+// Aurora のクラスターに置けるリーダーの上限と、Serverless v2 の ACU の範囲（0.5 刻み）
+const (
+	maxAuroraReaders  = 15
+	minServerlessACU  = 0.5
+	maxServerlessACU  = 256
+	serverlessACUStep = 0.5
+)
+
+func (e DatabaseEngine) problems(instanceType string) []error {
+	var errs []error
+
+	if !slices.Contains(database.Engines, e.Engine) {
+		errs = append(errs, fmt.Errorf("DB_ENGINE must be one of %v, got %q", database.Engines, e.Engine))
+	}
+	if e.Engine != database.EngineAuroraPostgres {
+		return errs
+	}
+	if e.AuroraReaders < 0 || e.AuroraReaders > maxAuroraReaders {
+		errs = append(errs, fmt.Errorf("DB_AURORA_READERS must be between 0 and %d, got %d", maxAuroraReaders, e.AuroraReaders))
+	}
+	if e.ServerlessMaxCapacity == 0 {
+		// Aurora PostgreSQL は micro / small のインスタンスクラスに対応していない
+		if strings.HasSuffix(instanceType, ".micro") || strings.HasSuffix(instanceType, ".small") {
+			errs = append(errs, fmt.Errorf("DB_INSTANCE_TYPE must be medium or larger for Aurora (e.g. t4g.medium), got %q", instanceType))
+		}
+		return errs
+	}
+	for _, acu := range []struct {
+		name  string
+		value float64
+	}{
+		{"DB_SERVERLESS_MIN_ACU", e.ServerlessMinCapacity},
+		{"DB_SERVERLESS_MAX_ACU", e.ServerlessMaxCapacity},
+	} {
+		if acu.value < minServerlessACU || acu.value > maxServerlessACU || math.Mod(acu.value, serverlessACUStep) != 0 {
+			errs = append(errs, fmt.Errorf("%s must be between %v and %v in steps of %v, got %v", acu.name, minServerlessACU, maxServerlessACU, serverlessACUStep, acu.value))
+		}
+	}
+	if e.ServerlessMinCapacity > e.ServerlessMaxCapacity {
+		errs = append(errs, fmt.Errorf("DB_SERVERLESS_MIN_ACU (%v) must not exceed DB_SERVERLESS_MAX_ACU (%v)", e.ServerlessMinCapacity, e.ServerlessMaxCapacity))
+	}
+
+	return errs
+}
+
+// DatabaseProxy は RDS Proxy の設定
+type DatabaseProxy struct {
+	// コンテナから DB への接続を RDS Proxy 経由にする（IAM 認証と TLS が必須になる）
+	Enabled bool
+	// DB の max_connections のうち、プロキシが使う接続の割合（%。0 の場合は 100）
+	MaxConnectionsPercent int
+}
+
+func (p DatabaseProxy) problems() []error {
+	if p.MaxConnectionsPercent < 0 || p.MaxConnectionsPercent > 100 {
+		return []error{fmt.Errorf("DB_PROXY_MAX_CONNECTIONS_PERCENT must be between 0 (default) and 100, got %d", p.MaxConnectionsPercent)}
+	}
+	return nil
+}
+
+// DatabaseProfile は RDS の可用性と削除時の保護の設定
+type DatabaseProfile struct {
+	// free-tier（シングル AZ、削除保護なし）または production（マルチ AZ、削除保護、削除時のスナップショット）
+	Profile database.Profile
+	// 自動バックアップとメンテナンスの時間帯（UTC。空の場合は AWS が決める）
+	BackupWindow      string
+	MaintenanceWindow string
+	// DB インスタンス（クラスター）ID。空の場合は <RESOURCE_NAME>-db。既存の DB の ID（database-1 など）を引き継ぐ場合に指定する
+	Identifier string
+}
+
+// ステージごとの既定値。prod のバックアップとメンテナンスは日本時間の深夜 3 時台と月曜 4 時台
+var defaultDatabaseProfile = map[Stage]DatabaseProfile{
+	StageDev: {
+		Profile: database.ProfileFreeTier,
+	},
+	StageStaging: {
+		Profile: database.ProfileFreeTier,
+	},
+	StageProd: {
+		Profile:           database.ProfileProduction,
+		BackupWindow:      "18:00-19:00",
+		MaintenanceWindow: "sun:19:00-sun:20:00",
+	},
+}
+
+func (p DatabaseProfile) problems(multiAz bool) []error {
+	var errs []error
+
+	if !slices.Contains(database.Profiles, p.Profile) {
+		errs = append(errs, fmt.Errorf("DB_PROFILE must be one of %v, got %q", database.Profiles, p.Profile))
+	}
+	// production はマルチ AZ で運用する（ライブラリは DB_MULTI_AZ の指定どおりに作る）
+	if p.Profile == database.ProfileProduction && !multiAz {
+		errs = append(errs, fmt.Errorf("DB_MULTI_AZ must be true when DB_PROFILE is %s", database.ProfileProduction))
+	}
+	if p.BackupWindow != "" && !backupWindowPattern.MatchString(p.BackupWindow) {
+		errs = append(errs, fmt.Errorf("DB_BACKUP_WINDOW must look like 18:00-19:00 (UTC), got %q", p.BackupWindow))
+	}
+	if p.MaintenanceWindow != "" && !maintenanceWindowPattern.MatchString(p.MaintenanceWindow) {
+		errs = append(errs, fmt.Errorf("DB_MAINTENANCE_WINDOW must look like sun:19:00-sun:20:00 (UTC), got %q", p.MaintenanceWindow))
+	}
+	if p.Identifier != "" && (len(p.Identifier) > 63 || !dbIdentifierPattern.MatchString(p.Identifier)) {
+		errs = append(errs, fmt.Errorf("DB_IDENTIFIER must start with a lower-case letter and contain only lower-case letters, digits and single hyphens (up to 63 characters), got %q", p.Identifier))
+	}
+
+	return errs
+}
+
+// DatabaseMonitoring は RDS の Performance Insights、拡張モニタリング、ログのエクスポートの設定
+type DatabaseMonitoring struct {
+	// off（無料利用枠）、basic、full
+	Level database.MonitoringLevel
+	// full の場合の Performance Insights の保持期間（月。0 は無料枠の 7 日、24 は 2 年）
+	PerformanceInsightsMonths int
+	// full の場合の拡張モニタリングの間隔（秒）
+	IntervalSeconds int
+	// この時間（ミリ秒）以上かかったクエリをログに出力する（0 の場合は出力しない）
+	SlowQueryMillis int
+}
+
+// ステージごとの既定値
+var defaultDatabaseMonitoring = map[Stage]DatabaseMonitoring{
+	StageDev: {
+		Level:           database.MonitoringLevelOff,
+		IntervalSeconds: 60,
+	},
+	StageStaging: {
+		Level:           database.MonitoringLevelBasic,
+		IntervalSeconds: 60,
+		SlowQueryMillis: 1000,
+	},
+	StageProd: {
+		Level:                     database.MonitoringLevelFull,
+		PerformanceInsightsMonths: 1,
+		IntervalSeconds:           60,
+		SlowQueryMillis:           500,
+	},
+}
+
+// 拡張モニタリングで指定できる間隔（秒）
+var monitoringIntervals = []int{1, 5, 10, 15, 30, 60}
+
+// Performance Insights の長期保持（2 年）
+const performanceInsightsLongTermMonths = 24
+
+// PerformanceInsightRetention は PerformanceInsightsMonths を Performance Insights の保持期間に変換する
+func (m DatabaseMonitoring) PerformanceInsightRetention() awsrds.PerformanceInsightRetention {
+	switch m.PerformanceInsightsMonths {
+	case 0:
+		return awsrds.PerformanceInsightRetention_DEFAULT
+	case performanceInsightsLongTermMonths:
+		return awsrds.PerformanceInsightRetention_LONG_TERM
+	}
+	return awsrds.PerformanceInsightRetention(fmt.Sprintf("MONTHS_%d", m.PerformanceInsightsMonths))
+}
+
+func (m DatabaseMonitoring) problems() []error {
+	var errs []error
+
+	if !slices.Contains(database.MonitoringLevels, m.Level) {
+		errs = append(errs, fmt.Errorf("DB_MONITORING must be one of %v, got %q", database.MonitoringLevels, m.Level))
+	}
+	if m.SlowQueryMillis < 0 {
+		errs = append(errs, fmt.Errorf("DB_SLOW_QUERY_MILLIS must not be negative, got %d", m.SlowQueryMillis))
+	}
+	if m.Level != database.MonitoringLevelFull {
+		return errs
+	}
+	if m.PerformanceInsightsMonths < 0 || m.PerformanceInsightsMonths > performanceInsightsLongTermMonths {
+		errs = append(errs, fmt.Errorf("DB_PERFORMANCE_INSIGHTS_MONTHS must be between 0 (7 days) and %d, got %d", performanceInsightsLongTermMonths, m.PerformanceInsightsMonths))
+	}
+	if !slices.Contains(monitoringIntervals, m.IntervalSeconds) {
+		errs = append(errs, fmt.Errorf("DB_MONITORING_INTERVAL must be one of %v, got %d", monitoringIntervals, m.IntervalSeconds))
+	}
+
+	return errs
+}
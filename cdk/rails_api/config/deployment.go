@@ -0,0 +1,126 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+
+	"iaclib/deployment"
+)
+
+// RollingDeployment は ECS のローリングデプロイの設定（DEPLOYMENT_MODE が rolling の場合に使う）
+type RollingDeployment struct {
+	// デプロイ中に維持するタスク数の下限と上限（DESIRED_COUNT に対する割合）
+	MinHealthyPercent int
+	MaxHealthyPercent int
+}
+
+var defaultRollingDeployment = RollingDeployment{
+	MinHealthyPercent: 100,
+	MaxHealthyPercent: 200,
+}
+
+func (r RollingDeployment) problems() []error {
+	var errs []error
+
+	if r.MinHealthyPercent < 0 || r.MinHealthyPercent > 100 {
+		errs = append(errs, fmt.Errorf("DEPLOYMENT_MIN_HEALTHY_PERCENT must be between 0 and 100, got %d", r.MinHealthyPercent))
+	}
+	// 上限が下限以下だと新しいタスクを起動できずデプロイが進まない
+	if r.MaxHealthyPercent < 100 || r.MaxHealthyPercent <= r.MinHealthyPercent {
+		errs = append(errs, fmt.Errorf("DEPLOYMENT_MAX_HEALTHY_PERCENT must be at least 100 and greater than DEPLOYMENT_MIN_HEALTHY_PERCENT (%d), got %d", r.MinHealthyPercent, r.MaxHealthyPercent))
+	}
+
+	return errs
+}
+
+// BlueGreenDeployment は CodeDeploy のブルーグリーンデプロイの設定（DEPLOYMENT_MODE が bluegreen の場合に使う）
+type BlueGreenDeployment struct {
+	// 本番トラフィックの切り替え方式と、linear / canary の割合（%）と間隔（分）
+	TrafficShifting                deployment.TrafficShiftingType
+	TrafficShiftingPercent         int
+	TrafficShiftingIntervalMinutes int
+	// TrafficShifting が custom の場合に使う既存のデプロイ設定名
+	DeploymentConfigName string
+	// 切り替え後、blue のタスクを終了するまでの待ち時間（分）
+	TerminationWaitMinutes int
+}
+
+// ステージごとの既定値
+var defaultBlueGreenDeployment = map[Stage]BlueGreenDeployment{
+	StageDev: {
+		TrafficShifting: deployment.TrafficShiftingAllAtOnce,
+	},
+	StageStaging: {
+		TrafficShifting:                deployment.TrafficShiftingLinear,
+		TrafficShiftingPercent:         10,
+		TrafficShiftingIntervalMinutes: 1,
+		TerminationWaitMinutes:         5,
+	},
+	StageProd: {
+		TrafficShifting:                deployment.TrafficShiftingCanary,
+		TrafficShiftingPercent:         10,
+		TrafficShiftingIntervalMinutes: 5,
+		// 切り替え後の問題に備え、すぐに戻せるよう1時間 blue を残す
+		TerminationWaitMinutes: 60,
+	},
+}
+
+// CodeDeploy で指定できる待ち時間の上限（2日）
+const maxDeploymentWaitMinutes = 2880
+
+func (b BlueGreenDeployment) problems() []error {
+	var errs []error
+
+	switch b.TrafficShifting {
+	case deployment.TrafficShiftingLinear, deployment.TrafficShiftingCanary:
+		if b.TrafficShiftingPercent < 1 || b.TrafficShiftingPercent > 99 {
+			errs = append(errs, fmt.Errorf("TRAFFIC_SHIFTING_PERCENT must be between 1 and 99, got %d", b.TrafficShiftingPercent))
+		}
+		if b.TrafficShiftingIntervalMinutes < 1 || b.TrafficShiftingIntervalMinutes > maxDeploymentWaitMinutes {
+			errs = append(errs, fmt.Errorf("TRAFFIC_SHIFTING_INTERVAL must be between 1 and %d minutes, got %d", maxDeploymentWaitMinutes, b.TrafficShiftingIntervalMinutes))
+		}
+	case deployment.TrafficShiftingCustom:
+		if b.DeploymentConfigName == "" {
+			errs = append(errs, fmt.Errorf("CODEDEPLOY_DEPLOYMENT_CONFIG is required when TRAFFIC_SHIFTING is %s", deployment.TrafficShiftingCustom))
+		}
+	default:
+		if !slices.Contains(deployment.TrafficShiftingTypes, b.TrafficShifting) {
+			errs = append(errs, fmt.Errorf("TRAFFIC_SHIFTING must be one of %v, got %q", deployment.TrafficShiftingTypes, b.TrafficShifting))
+		}
+	}
+	if b.TerminationWaitMinutes < 0 || b.TerminationWaitMinutes > maxDeploymentWaitMinutes {
+		errs = append(errs, fmt.Errorf("BLUE_TERMINATION_WAIT must be between 0 and %d minutes, got %d", maxDeploymentWaitMinutes, b.TerminationWaitMinutes))
+	}
+
+	return errs
+}
+
+// DeploymentAlarms はデプロイ中にロールバックするしきい値（0 の項目はアラームを作らない）。
+// rolling ではサービスのターゲットグループ、bluegreen では blue / green 両方のターゲットグループを監視する
+type DeploymentAlarms struct {
+	Max5xxRatePercent     int
+	MaxResponseTimeMillis int
+	MaxUnhealthyHosts     int
+}
+
+var defaultDeploymentAlarms = DeploymentAlarms{
+	Max5xxRatePercent:     5,
+	MaxResponseTimeMillis: 2000,
+	MaxUnhealthyHosts:     1,
+}
+
+func (a DeploymentAlarms) problems() []error {
+	var errs []error
+
+	if a.Max5xxRatePercent < 0 || a.Max5xxRatePercent > 100 {
+		errs = append(errs, fmt.Errorf("DEPLOYMENT_ALARM_5XX_RATE must be between 0 (disabled) and 100, got %d", a.Max5xxRatePercent))
+	}
+	if a.MaxResponseTimeMillis < 0 {
+		errs = append(errs, fmt.Errorf("DEPLOYMENT_ALARM_RESPONSE_TIME must not be negative, got %d", a.MaxResponseTimeMillis))
+	}
+	if a.MaxUnhealthyHosts < 0 {
+		errs = append(errs, fmt.Errorf("DEPLOYMENT_ALARM_UNHEALTHY_HOSTS must not be negative, got %d", a.MaxUnhealthyHosts))
+	}
+
+	return errs
+}
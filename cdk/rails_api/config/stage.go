@@ -0,0 +1,150 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslogs"
+)
+
+// Stage はデプロイ先の環境
+type Stage string
+
+const (
+	StageDev     Stage = "dev"
+	StageStaging Stage = "staging"
+	StageProd    Stage = "prod"
+)
+
+// Stages は synth 対象になりうる全ステージ
+var Stages = []Stage{StageDev, StageStaging, StageProd}
+
+// ParseStages はカンマ区切りのステージ指定を解釈する。空の場合は全ステージを返す
+func ParseStages(value string) ([]Stage, error) {
+	if strings.TrimSpace(value) == "" {
+		return Stages, nil
+	}
+
+	var stages []Stage
+	for _, v := range strings.Split(value, ",") {
+		stage := Stage(strings.TrimSpace(v))
+		if !stage.valid() {
+			return nil, fmt.Errorf("unknown stage %q (expected one of %v)", stage, Stages)
+		}
+		stages = append(stages, stage)
+	}
+	return stages, nil
+}
+
+func (s Stage) valid() bool {
+	for _, stage := range Stages {
+		if s == stage {
+			return true
+		}
+	}
+	return false
+}
+
+// Sizing はステージごとに変えるリソースのサイズ
+type Sizing struct {
+	Cpu                   int
+	MemoryLimitMiB        int
+	DesiredCount          int
+	LogRetentionDays      int
+	DBInstanceType        string
+	DBMultiAz             bool
+	DBBackupRetentionDays int
+}
+
+// ステージごとの既定値。.env.<stage> で個別に上書きできる
+var defaultSizing = map[Stage]Sizing{
+	StageDev: {
+		Cpu:                   256,
+		MemoryLimitMiB:        512,
+		DesiredCount:          1,
+		LogRetentionDays:      7,
+		DBInstanceType:        "t3.micro", // 無料利用枠
+		DBMultiAz:             false,
+		DBBackupRetentionDays: 1,
+	},
+	StageStaging: {
+		Cpu:                   512,
+		MemoryLimitMiB:        1024,
+		DesiredCount:          1,
+		LogRetentionDays:      14,
+		DBInstanceType:        "t3.small",
+		DBMultiAz:             false,
+		DBBackupRetentionDays: 7,
+	},
+	StageProd: {
+		Cpu:                   1024,
+		MemoryLimitMiB:        2048,
+		DesiredCount:          2,
+		LogRetentionDays:      90,
+		DBInstanceType:        "t3.medium",
+		DBMultiAz:             true,
+		DBBackupRetentionDays: 14,
+	},
+}
+
+var logRetentionDays = map[int]awslogs.RetentionDays{
+	1:   awslogs.RetentionDays_ONE_DAY,
+	3:   awslogs.RetentionDays_THREE_DAYS,
+	5:   awslogs.RetentionDays_FIVE_DAYS,
+	7:   awslogs.RetentionDays_ONE_WEEK,
+	14:  awslogs.RetentionDays_TWO_WEEKS,
+	30:  awslogs.RetentionDays_ONE_MONTH,
+	60:  awslogs.RetentionDays_TWO_MONTHS,
+	90:  awslogs.RetentionDays_THREE_MONTHS,
+	120: awslogs.RetentionDays_FOUR_MONTHS,
+	150: awslogs.RetentionDays_FIVE_MONTHS,
+	180: awslogs.RetentionDays_SIX_MONTHS,
+	365: awslogs.RetentionDays_ONE_YEAR,
+	400: awslogs.RetentionDays_THIRTEEN_MONTHS,
+	545: awslogs.RetentionDays_EIGHTEEN_MONTHS,
+	731: awslogs.RetentionDays_TWO_YEARS,
+}
+
+// LogRetention は LogRetentionDays を CloudWatch Logs の保持期間に変換する
+func (s Sizing) LogRetention() awslogs.RetentionDays {
+	return logRetentionDays[s.LogRetentionDays]
+}
+
+func (s Sizing) problems() []error {
+	var errs []error
+
+	if !validFargateSize(s.Cpu, s.MemoryLimitMiB) {
+		errs = append(errs, fmt.Errorf("FARGATE_CPU=%d and FARGATE_MEMORY=%d is not a valid Fargate combination", s.Cpu, s.MemoryLimitMiB))
+	}
+	if s.DesiredCount < 0 {
+		errs = append(errs, fmt.Errorf("DESIRED_COUNT must not be negative, got %d", s.DesiredCount))
+	}
+	if _, ok := logRetentionDays[s.LogRetentionDays]; !ok {
+		errs = append(errs, fmt.Errorf("LOG_RETENTION_DAYS must be a CloudWatch Logs retention period such as 7, 14 or 30, got %d", s.LogRetentionDays))
+	}
+	if s.DBInstanceType == "" {
+		errs = append(errs, fmt.Errorf("DB_INSTANCE_TYPE is required"))
+	}
+	if s.DBBackupRetentionDays < 1 || s.DBBackupRetentionDays > 35 {
+		errs = append(errs, fmt.Errorf("DB_BACKUP_RETENTION_DAYS must be between 1 and 35, got %d", s.DBBackupRetentionDays))
+	}
+
+	return errs
+}
+
+// Fargate で指定できる CPU とメモリの組み合わせ
+func validFargateSize(cpu, memory int) bool {
+	switch cpu {
+	case 256:
+		return memory == 512 || memory == 1024 || memory == 2048
+	case 512:
+		return memory >= 1024 && memory <= 4096 && memory%1024 == 0
+	case 1024:
+		return memory >= 2048 && memory <= 8192 && memory%1024 == 0
+	case 2048:
+		return memory >= 4096 && memory <= 16384 && memory%1024 == 0
+	case 4096:
+		return memory >= 8192 && memory <= 30720 && memory%1024 == 0
+	}
+	return false
+}
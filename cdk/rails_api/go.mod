@@ -8,7 +8,7 @@ require (
 	github.com/aws/aws-cdk-go/awscdk/v2 v2.180.0
 	github.com/aws/constructs-go/constructs/v10 v10.4.2
 	github.com/aws/jsii-runtime-go v1.106.0
-	github.com/joho/godotenv v1.5.1
+	github.com/joho/godotenv v1.5.1 // indirect
 )
 
 require (
@@ -26,3 +26,7 @@ require (
 	golang.org/x/sys v0.28.0 // indirect
 	golang.org/x/tools v0.28.0 // indirect
 )
+
+require iaclib v0.0.0
+
+replace iaclib => ../iaclib
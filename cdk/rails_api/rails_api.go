@@ -1,58 +1,402 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"log"
 	"os"
 
-	"rails_api/components/network"
-	"rails_api/components/rds"
-	"rails_api/components/service"
+	"rails_api/config"
+
+	"iaclib/database"
+	"iaclib/deployment"
+	"iaclib/monitoring"
+	"iaclib/network"
+	"iaclib/observability"
+	"iaclib/security"
+	"iaclib/service"
 
 	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsecs"
 	"github.com/aws/constructs-go/constructs/v10"
 	"github.com/aws/jsii-runtime-go"
-	"github.com/joho/godotenv"
 )
 
 type RailsApiStackProps struct {
 	awscdk.StackProps
+	Config *config.Config
+}
+
+// RailsApiStacks はステージごとのスタック一式
+// サービスの変更で DB を巻き込まないよう、ネットワーク・データ・アプリケーションを別スタックに分ける
+type RailsApiStacks struct {
+	Network *NetworkStack
+	Data    *DataStack
+	App     *AppStack
 }
 
-func NewRailsApiStack(scope constructs.Construct, id string, props *RailsApiStackProps) awscdk.Stack {
-	var sprops awscdk.StackProps
-	if props != nil {
-		sprops = props.StackProps
+func NewRailsApiStacks(scope constructs.Construct, prefix string, props *RailsApiStackProps) *RailsApiStacks {
+	networkStack := NewNetworkStack(scope, prefix+"-network", props)
+
+	dataStack := NewDataStack(scope, prefix+"-data", &DataStackProps{
+		RailsApiStackProps: *props,
+		Network:            networkStack.Network,
+	})
+	dataStack.AddDependency(networkStack.Stack, jsii.String("RDS is placed in the VPC"))
+
+	appStack := NewAppStack(scope, prefix+"-app", &AppStackProps{
+		RailsApiStackProps: *props,
+		Network:            networkStack.Network,
+		Database:           dataStack.Database,
+	})
+	appStack.AddDependency(networkStack.Stack, jsii.String("ECS service is registered to the ALB"))
+	appStack.AddDependency(dataStack.Stack, jsii.String("ECS service connects to RDS"))
+
+	// 管理者相当の管理ポリシーをアタッチしたロールがあれば synth を失敗させる
+	for _, stack := range []awscdk.Stack{networkStack.Stack, dataStack.Stack, appStack.Stack} {
+		awscdk.Aspects_Of(stack).Add(security.NewManagedPolicyGuard(), nil)
 	}
+
+	return &RailsApiStacks{
+		Network: networkStack,
+		Data:    dataStack,
+		App:     appStack,
+	}
+}
+
+// NetworkStack は VPC、セキュリティグループ、ALB、リスナー、ターゲットグループを持つ
+type NetworkStack struct {
+	awscdk.Stack
+	Network *network.AppNetwork
+}
+
+func NewNetworkStack(scope constructs.Construct, id string, props *RailsApiStackProps) *NetworkStack {
+	sprops := props.StackProps
 	stack := awscdk.NewStack(scope, &id, &sprops)
+	cfg := props.Config
 
-	network := network.NewNetwork(stack)
+	blueGreen := cfg.DeploymentMode == config.DeploymentModeBlueGreen
 
-	rds.NewRDS(stack, network)
+	var testListener *network.TestListenerProps
+	if blueGreen {
+		// テストリスナーはオフィスからのみ許可する
+		testListener = &network.TestListenerProps{
+			Port:         cfg.TestListenerPort,
+			AllowedCidrs: cfg.OfficeCidrs,
+		}
+	}
 
-	service.NewService(stack, network)
+	network := network.NewAppNetwork(stack, "Network", &network.AppNetworkProps{
+		ResourceName:       cfg.ResourceName,
+		InterfaceEndpoints: cfg.InterfaceEndpoints,
+		IsolatedSubnets:    true,
+		ContainerPort:      3000,
+		HealthCheckPath:    "/up",
+		Domain: &network.DomainProps{
+			DomainName:     cfg.DomainName,
+			HostedZoneName: cfg.HostedZoneName,
+		},
+		TestListener: testListener,
+	})
 
-	return stack
+	return &NetworkStack{
+		Stack:   stack,
+		Network: network,
+	}
 }
 
-func main() {
-	defer jsii.Close()
+type DataStackProps struct {
+	RailsApiStackProps
+	Network *network.AppNetwork
+}
 
-	// 環境変数読み込み
-	godotenv.Load()
+// DataStack は RDS と DB の認証情報を持つ。誤って削除しないよう終了保護を有効にする
+type DataStack struct {
+	awscdk.Stack
+	Database *database.PostgresDatabase
+}
 
-	app := awscdk.NewApp(nil)
+func NewDataStack(scope constructs.Construct, id string, props *DataStackProps) *DataStack {
+	sprops := props.StackProps
+	sprops.TerminationProtection = jsii.Bool(true)
+	stack := awscdk.NewStack(scope, &id, &sprops)
+	cfg := props.Config
 
-	NewRailsApiStack(app, "rails-api-stack", &RailsApiStackProps{
-		StackProps: awscdk.StackProps{
-			Env: env(),
+	// RDS のインスタンスまたは Aurora のクラスター（サイズはステージごとの設定に従う）
+	rds := database.NewPostgresDatabase(stack, "Database", &database.PostgresDatabaseProps{
+		ResourceName:         cfg.ResourceName,
+		Vpc:                  props.Network.Vpc,
+		ClientSecurityGroups: []awsec2.ISecurityGroup{props.Network.EcsSecurityGroup},
+		DatabaseName:         "rails_api_production",
+		Username:             cfg.DBUsername,
+		Engine:               cfg.DBEngine.Engine,
+		Aurora: database.AuroraProps{
+			Readers:                 cfg.DBEngine.AuroraReaders,
+			ServerlessV2MinCapacity: cfg.DBEngine.ServerlessMinCapacity,
+			ServerlessV2MaxCapacity: cfg.DBEngine.ServerlessMaxCapacity,
 		},
+		InstanceType:        cfg.Sizing.DBInstanceType,
+		MultiAz:             cfg.Sizing.DBMultiAz,
+		BackupRetentionDays: cfg.Sizing.DBBackupRetentionDays,
+		RotationDays:        cfg.DBRotationDays,
+		Profile:             cfg.DBProfile.Profile,
+		BackupWindow:        cfg.DBProfile.BackupWindow,
+		MaintenanceWindow:   cfg.DBProfile.MaintenanceWindow,
+		Identifier:          cfg.DBProfile.Identifier,
+		Monitoring: database.MonitoringProps{
+			Level:                             cfg.DBMonitoring.Level,
+			PerformanceInsightRetention:       cfg.DBMonitoring.PerformanceInsightRetention(),
+			EnhancedMonitoringIntervalSeconds: cfg.DBMonitoring.IntervalSeconds,
+			LogRetention:                      cfg.Sizing.LogRetention(),
+			SlowQueryMillis:                   cfg.DBMonitoring.SlowQueryMillis,
+		},
+		Proxy: proxyProps(cfg.DBProxy),
 	})
+	if cfg.DBProfile.Profile != database.ProfileProduction {
+		security.Suppress(rds, security.RuleStatefulRemovalPolicy, "スタックを作り直せるよう、free-tier の DB はスナップショットを残さずに削除する")
+	}
+
+	return &DataStack{
+		Stack:    stack,
+		Database: rds,
+	}
+}
+
+type AppStackProps struct {
+	RailsApiStackProps
+	Network  *network.AppNetwork
+	Database *database.PostgresDatabase
+}
+
+// AppStack は ECS サービスと（ブルーグリーンデプロイ時は）CodeDeploy を持つ
+type AppStack struct {
+	awscdk.Stack
+	Service       *service.FargateWebService
+	Deployment    *deployment.BlueGreenDeployment
+	Observability *observability.Observability
+}
+
+func NewAppStack(scope constructs.Construct, id string, props *AppStackProps) *AppStack {
+	sprops := props.StackProps
+	stack := awscdk.NewStack(scope, &id, &sprops)
+	cfg := props.Config
+	network := props.Network
+	rds := props.Database
+
+	blueGreen := cfg.DeploymentMode == config.DeploymentModeBlueGreen
+
+	environment := map[string]*string{
+		"TZ":                       jsii.String("Asia/Tokyo"),
+		"RAILS_ENV":                jsii.String("production"),
+		"RAILS_SERVE_STATIC_FILES": jsii.String("true"),
+		"RAILS_MASTER_KEY":         jsii.String(cfg.RailsMasterKey),
+		"DB_HOST":                  rds.Endpoint.Hostname(),
+		"DB_PORT":                  awscdk.Tokenization_StringifyNumber(rds.Endpoint.Port()),
+		// 読み取りの多いエンドポイントはリーダーに接続する（単一のインスタンスの場合は DB_HOST と同じ）
+		"DB_READER_HOST": rds.ReaderEndpoint.Hostname(),
+		"ALLOWED_ORIGIN": jsii.String(cfg.AllowedOrigin),
+	}
+	if rds.Proxy != nil {
+		// RDS Proxy には IAM 認証のトークンで TLS 接続する
+		environment["DB_IAM_AUTH"] = jsii.String("true")
+		environment["DB_SSLMODE"] = jsii.String("require")
+	}
+
+	service := service.NewFargateWebService(stack, "Service", &service.FargateWebServiceProps{
+		ResourceName:   cfg.ResourceName,
+		Vpc:            network.Vpc,
+		SecurityGroup:  network.EcsSecurityGroup,
+		TargetGroup:    network.TargetGroup1,
+		RepositoryName: cfg.RepositoryName,
+		ContainerName:  "rails",
+		ContainerPort:  3000,
+		Cpu:            cfg.Sizing.Cpu,
+		MemoryLimitMiB: cfg.Sizing.MemoryLimitMiB,
+		DesiredCount:   cfg.Sizing.DesiredCount,
+		Environment:    environment,
+		// DB の認証情報は Secrets Manager から起動時に注入する
+		Secrets: map[string]awsecs.Secret{
+			"DB_USERNAME": awsecs.Secret_FromSecretsManager(rds.Secret, jsii.String("username")),
+			"DB_PASSWORD": awsecs.Secret_FromSecretsManager(rds.Secret, jsii.String("password")),
+		},
+		LogRetention:           cfg.Sizing.LogRetention(),
+		BlueGreen:              blueGreen,
+		HealthCheckGracePeriod: awscdk.Duration_Seconds(jsii.Number(cfg.HealthCheckGracePeriodSeconds)),
+		// 失敗したローリングデプロイは前のタスク定義に自動で戻す
+		RollingDeployment: &service.RollingDeploymentProps{
+			MinHealthyPercent: cfg.RollingDeployment.MinHealthyPercent,
+			MaxHealthyPercent: cfg.RollingDeployment.MaxHealthyPercent,
+			Alarms:            deploymentAlarms(cfg.DeploymentAlarms),
+		},
+		AutoScaling: autoScalingProps(cfg.AutoScaling),
+		// ダッシュボードに実行中のタスク数を表示する
+		ContainerInsights: true,
+	})
+
+	if rds.Proxy != nil {
+		rds.Proxy.GrantConnect(service.TaskRole, jsii.String(cfg.DBUsername))
+	}
+
+	var bgDeployment *deployment.BlueGreenDeployment
+	if blueGreen {
+		alarms := deploymentAlarms(cfg.DeploymentAlarms)
+		bgDeployment = deployment.NewBlueGreenDeployment(stack, "Deployment", &deployment.BlueGreenDeploymentProps{
+			ResourceName:     cfg.ResourceName,
+			Service:          service.Service,
+			TaskDefinition:   service.TaskDef,
+			BlueTargetGroup:  network.TargetGroup1,
+			GreenTargetGroup: network.TargetGroup2,
+			ProdListener:     network.ProdListener,
+			TestListener:     network.TestListener,
+			TrafficShifting: &deployment.TrafficShiftingProps{
+				Type:                 cfg.BlueGreen.TrafficShifting,
+				Percentage:           cfg.BlueGreen.TrafficShiftingPercent,
+				IntervalMinutes:      cfg.BlueGreen.TrafficShiftingIntervalMinutes,
+				DeploymentConfigName: cfg.BlueGreen.DeploymentConfigName,
+			},
+			// 失敗したデプロイやアラームが発生したデプロイは blue に戻す
+			Alarms:              &alarms,
+			TerminationWaitTime: awscdk.Duration_Minutes(jsii.Number(cfg.BlueGreen.TerminationWaitMinutes)),
+		})
+	}
+
+	// ALB、ECS、RDS のダッシュボードと、SNS に通知するアラーム
+	observability := observability.NewObservability(stack, "Observability", &observability.ObservabilityProps{
+		ResourceName: cfg.ResourceName,
+		Network:      network,
+		Service:      service,
+		Database:     rds,
+		Thresholds:   alarmThresholds(cfg.Alarms),
+		AlarmEmails:  cfg.Alarms.Emails,
+	})
+
+	return &AppStack{
+		Stack:         stack,
+		Service:       service,
+		Deployment:    bgDeployment,
+		Observability: observability,
+	}
+}
+
+func deploymentAlarms(a config.DeploymentAlarms) monitoring.TargetGroupThresholds {
+	return monitoring.TargetGroupThresholds{
+		Max5xxRatePercent:     a.Max5xxRatePercent,
+		MaxResponseTimeMillis: a.MaxResponseTimeMillis,
+		MaxUnhealthyHosts:     a.MaxUnhealthyHosts,
+	}
+}
+
+// Fargate のタスク数が増えても DB の接続数を抑えられるよう、接続を RDS Proxy でプールする
+func proxyProps(p config.DatabaseProxy) *database.ProxyProps {
+	if !p.Enabled {
+		return nil
+	}
+	return &database.ProxyProps{
+		MaxConnectionsPercent: p.MaxConnectionsPercent,
+	}
+}
+
+func alarmThresholds(a config.Alarms) observability.AlarmThresholds {
+	return observability.AlarmThresholds{
+		Max5xxRatePercent:       a.Max5xxRatePercent,
+		MaxResponseTimeMillis:   a.MaxResponseTimeMillis,
+		MaxServiceCpuPercent:    a.MaxServiceCpuPercent,
+		MaxServiceMemoryPercent: a.MaxServiceMemoryPercent,
+		MaxDBCpuPercent:         a.MaxDBCpuPercent,
+		MaxDBConnections:        a.MaxDBConnections,
+		MinDBFreeStorageMiB:     a.MinDBFreeStorageMiB,
+		MaxDBReadLatencyMillis:  a.MaxDBReadLatencyMillis,
+		MaxDBWriteLatencyMillis: a.MaxDBWriteLatencyMillis,
+	}
+}
+
+// 業務時間帯のアクセス増に合わせてタスク数を調整する
+func autoScalingProps(a config.AutoScaling) *service.AutoScalingProps {
+	props := &service.AutoScalingProps{
+		MinCapacity:       a.MinCapacity,
+		MaxCapacity:       a.MaxCapacity,
+		CpuUtilization:    a.CpuTarget,
+		MemoryUtilization: a.MemoryTarget,
+		RequestsPerTarget: a.RequestsPerTarget,
+		ScaleInCooldown:   awscdk.Duration_Seconds(jsii.Number(a.ScaleInCooldownSeconds)),
+		ScaleOutCooldown:  awscdk.Duration_Seconds(jsii.Number(a.ScaleOutCooldownSeconds)),
+	}
+	if a.Scheduled() {
+		props.Schedules = []service.ScheduledCapacity{
+			{
+				Name:        "ScaleToZero",
+				Cron:        a.ScaleToZeroCron,
+				TimeZone:    a.ScheduleTimeZone,
+				MinCapacity: 0,
+				MaxCapacity: 0,
+			},
+			{
+				Name:        "ScaleUp",
+				Cron:        a.ScaleUpCron,
+				TimeZone:    a.ScheduleTimeZone,
+				MinCapacity: a.MinCapacity,
+				MaxCapacity: a.MaxCapacity,
+			},
+		}
+	}
+	return props
+}
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run() error {
+	defer jsii.Close()
+
+	app := awscdk.NewApp(nil)
+	// 暗号化、公開ポート、IAM のワイルドカードなどを検査し、違反があれば synth を失敗させる
+	awscdk.Aspects_Of(app).Add(security.NewComplianceChecks(), nil)
+
+	// 対象ステージは `cdk deploy -c stage=prod`（複数の場合は stage=dev,prod）か環境変数 STAGE で指定する（未指定なら全ステージ）。
+	// 対象のステージのどれかの設定に誤りがあれば、まとめて報告して synth を止める
+	stages, err := config.ParseStages(selectedStages(app))
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, stage := range stages {
+		// 環境変数読み込み
+		cfg, err := config.Load(stage)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("stage %s: %w", stage, err))
+			continue
+		}
+
+		NewRailsApiStacks(app, "rails-api-"+string(stage), &RailsApiStackProps{
+			StackProps: awscdk.StackProps{
+				Env: env(cfg),
+			},
+			Config: cfg,
+		})
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
 
 	app.Synth(nil)
+	return nil
+}
+
+func selectedStages(app awscdk.App) string {
+	if v, ok := app.Node().TryGetContext(jsii.String("stage")).(string); ok {
+		return v
+	}
+	return os.Getenv("STAGE")
 }
 
-func env() *awscdk.Environment {
+func env(cfg *config.Config) *awscdk.Environment {
 	return &awscdk.Environment{
-		Account: jsii.String(os.Getenv("ACCOUNT_ID")),
-		Region:  jsii.String(os.Getenv("REGION")),
+		Account: jsii.String(cfg.AccountID),
+		Region:  jsii.String(cfg.Region),
 	}
 }
@@ -1,26 +1,1181 @@
 package main
 
-// import (
-// 	"testing"
-
-// 	"github.com/aws/aws-cdk-go/awscdk/v2"
-// 	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
-// 	"github.com/aws/jsii-runtime-go"
-// )
-
-// example tests. To run these tests, uncomment this file along with the
-// example resource in rails_api_test.go
-// func TestRailsApiStack(t *testing.T) {
-// 	// GIVEN
-// 	app := awscdk.NewApp(nil)
-
-// 	// WHEN
-// 	stack := NewRailsApiStack(app, "MyStack", nil)
-
-// 	// THEN
-// 	template := assertions.Template_FromStack(stack, nil)
-
-// 	template.HasResourceProperties(jsii.String("AWS::SQS::Queue"), map[string]interface{}{
-// 		"VisibilityTimeout": 300,
-// 	})
-// }
+import (
+	"strings"
+	"testing"
+
+	"rails_api/config"
+
+	"iaclib/database"
+	"iaclib/deployment"
+	"iaclib/security"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	"github.com/aws/jsii-runtime-go"
+)
+
+const (
+	testAccount = "123456789012"
+	testRegion  = "ap-northeast-1"
+)
+
+func testConfig() *config.Config {
+	return &config.Config{
+		Stage:          config.StageDev,
+		AccountID:      testAccount,
+		Region:         testRegion,
+		ResourceName:   "rails-api-dev",
+		RepositoryName: "rails-api",
+		RailsMasterKey: "dummy-master-key",
+		DomainName:     "api.example.com",
+		HostedZoneName: "example.com",
+		DBUsername:     "postgres",
+		AllowedOrigin:  "https://example.com",
+		DBEngine: config.DatabaseEngine{
+			Engine: database.EnginePostgres,
+		},
+		DBProfile: config.DatabaseProfile{
+			Profile: database.ProfileFreeTier,
+		},
+		DBMonitoring: config.DatabaseMonitoring{
+			Level:           database.MonitoringLevelOff,
+			IntervalSeconds: 60,
+		},
+		InterfaceEndpoints: []string{
+			"ecr.api",
+			"ecr.dkr",
+			"logs",
+			"secretsmanager",
+		},
+		DeploymentMode: config.DeploymentModeRolling,
+		RollingDeployment: config.RollingDeployment{
+			MinHealthyPercent: 100,
+			MaxHealthyPercent: 200,
+		},
+		BlueGreen: config.BlueGreenDeployment{
+			TrafficShifting: deployment.TrafficShiftingAllAtOnce,
+		},
+		DeploymentAlarms: config.DeploymentAlarms{
+			Max5xxRatePercent:     5,
+			MaxResponseTimeMillis: 2000,
+			MaxUnhealthyHosts:     1,
+		},
+		Alarms: config.Alarms{
+			Max5xxRatePercent:       10,
+			MaxResponseTimeMillis:   3000,
+			MaxServiceCpuPercent:    90,
+			MaxServiceMemoryPercent: 90,
+			MaxDBCpuPercent:         90,
+			MaxDBConnections:        70,
+			MinDBFreeStorageMiB:     2048,
+			MaxDBReadLatencyMillis:  50,
+			MaxDBWriteLatencyMillis: 50,
+		},
+		HealthCheckGracePeriodSeconds: 120,
+		TestListenerPort:              8443,
+		Sizing: config.Sizing{
+			Cpu:                   256,
+			MemoryLimitMiB:        512,
+			DesiredCount:          1,
+			LogRetentionDays:      7,
+			DBInstanceType:        "t3.micro",
+			DBMultiAz:             false,
+			DBBackupRetentionDays: 7,
+		},
+		AutoScaling: config.AutoScaling{
+			MinCapacity:             1,
+			MaxCapacity:             2,
+			CpuTarget:               60,
+			MemoryTarget:            75,
+			RequestsPerTarget:       1000,
+			ScaleInCooldownSeconds:  300,
+			ScaleOutCooldownSeconds: 60,
+			ScaleToZeroCron:         "0 22 * * ? *",
+			ScaleUpCron:             "0 8 ? * MON-FRI *",
+			ScheduleTimeZone:        "Asia/Tokyo",
+		},
+	}
+}
+
+// ネットワークへの問い合わせが発生しないよう、ルックアップ結果をコンテキストで固定する
+func testContext(region string) *map[string]interface{} {
+	return &map[string]interface{}{
+		"availability-zones:account=" + testAccount + ":region=" + region: []string{
+			region + "a",
+			region + "c",
+			region + "d",
+		},
+		"hosted-zone:account=" + testAccount + ":domainName=example.com:region=" + region: map[string]interface{}{
+			"Id":   "/hostedzone/Z0000000000000000000",
+			"Name": "example.com.",
+		},
+	}
+}
+
+// スタックごとのテンプレート
+type railsApiTemplates struct {
+	Network assertions.Template
+	Data    assertions.Template
+	App     assertions.Template
+}
+
+func synthRailsApiStacks(t *testing.T, cfg *config.Config) (*RailsApiStacks, railsApiTemplates) {
+	t.Helper()
+
+	app := awscdk.NewApp(&awscdk.AppProps{
+		Context: testContext(cfg.Region),
+	})
+
+	stacks := NewRailsApiStacks(app, "rails-api-test", &RailsApiStackProps{
+		StackProps: awscdk.StackProps{
+			Env: env(cfg),
+		},
+		Config: cfg,
+	})
+
+	return stacks, railsApiTemplates{
+		Network: assertions.Template_FromStack(stacks.Network.Stack, nil),
+		Data:    assertions.Template_FromStack(stacks.Data.Stack, nil),
+		App:     assertions.Template_FromStack(stacks.App.Stack, nil),
+	}
+}
+
+func TestRailsApiStack(t *testing.T) {
+	// GIVEN
+	cfg := testConfig()
+
+	// WHEN
+	_, templates := synthRailsApiStacks(t, cfg)
+
+	// THEN
+	t.Run("vpc has public, private and isolated subnets in two AZs", func(t *testing.T) {
+		templates.Network.ResourceCountIs(jsii.String("AWS::EC2::VPC"), jsii.Number(1))
+		templates.Network.ResourceCountIs(jsii.String("AWS::EC2::Subnet"), jsii.Number(6))
+		templates.Network.ResourceCountIs(jsii.String("AWS::EC2::NatGateway"), jsii.Number(0))
+		for _, name := range []string{"public", "private", "isolated"} {
+			templates.Network.ResourcePropertiesCountIs(jsii.String("AWS::EC2::Subnet"), map[string]interface{}{
+				"Tags": assertions.Match_ArrayWith(&[]interface{}{
+					map[string]interface{}{"Key": "aws-cdk:subnet-name", "Value": name},
+				}),
+			}, jsii.Number(2))
+		}
+	})
+
+	t.Run("security groups only open the expected ports", func(t *testing.T) {
+		templates.Network.HasResourceProperties(jsii.String("AWS::EC2::SecurityGroup"), map[string]interface{}{
+			"GroupName": "rails-api-dev-sg-alb",
+			"SecurityGroupIngress": []interface{}{
+				assertions.Match_ObjectLike(&map[string]interface{}{"CidrIp": "0.0.0.0/0", "FromPort": 80, "ToPort": 80}),
+				assertions.Match_ObjectLike(&map[string]interface{}{"CidrIp": "0.0.0.0/0", "FromPort": 443, "ToPort": 443}),
+			},
+		})
+		templates.Network.HasResourceProperties(jsii.String("AWS::EC2::SecurityGroupIngress"), map[string]interface{}{
+			"Description": "http from alb",
+			"FromPort":    3000,
+			"ToPort":      3000,
+		})
+		templates.Data.HasResourceProperties(jsii.String("AWS::EC2::SecurityGroupIngress"), map[string]interface{}{
+			"Description": "PostgreSQL from client",
+			"FromPort":    5432,
+			"ToPort":      5432,
+		})
+	})
+
+	t.Run("https listener and http redirect", func(t *testing.T) {
+		templates.Network.HasResourceProperties(jsii.String("AWS::ElasticLoadBalancingV2::Listener"), map[string]interface{}{
+			"Port":         443,
+			"Protocol":     "HTTPS",
+			"Certificates": assertions.Match_AnyValue(),
+		})
+		templates.Network.HasResourceProperties(jsii.String("AWS::ElasticLoadBalancingV2::Listener"), map[string]interface{}{
+			"Port":     80,
+			"Protocol": "HTTP",
+			"DefaultActions": []interface{}{
+				map[string]interface{}{
+					"Type": "redirect",
+					"RedirectConfig": map[string]interface{}{
+						"Protocol":   "HTTPS",
+						"Port":       "443",
+						"StatusCode": "HTTP_302",
+					},
+				},
+			},
+		})
+		templates.Network.HasResourceProperties(jsii.String("AWS::CertificateManager::Certificate"), map[string]interface{}{
+			"DomainName":       "api.example.com",
+			"ValidationMethod": "DNS",
+		})
+	})
+
+	t.Run("target group health check", func(t *testing.T) {
+		templates.Network.HasResourceProperties(jsii.String("AWS::ElasticLoadBalancingV2::TargetGroup"), map[string]interface{}{
+			"Name":                       "rails-api-dev-tg1",
+			"Port":                       3000,
+			"TargetType":                 "ip",
+			"HealthCheckPath":            "/up",
+			"HealthCheckIntervalSeconds": 60,
+			"HealthCheckTimeoutSeconds":  30,
+		})
+	})
+
+	t.Run("fargate task and service sizing", func(t *testing.T) {
+		templates.App.HasResourceProperties(jsii.String("AWS::ECS::TaskDefinition"), map[string]interface{}{
+			"Cpu":                     "256",
+			"Memory":                  "512",
+			"RequiresCompatibilities": []interface{}{"FARGATE"},
+		})
+		templates.App.HasResourceProperties(jsii.String("AWS::ECS::Service"), map[string]interface{}{
+			"ServiceName":  "rails-api-dev-service",
+			"DesiredCount": 1,
+			"LaunchType":   "FARGATE",
+		})
+	})
+
+	t.Run("rolling deployment by default", func(t *testing.T) {
+		templates.App.HasResourceProperties(jsii.String("AWS::ECS::Service"), map[string]interface{}{
+			"DeploymentController": map[string]interface{}{
+				"Type": "ECS",
+			},
+		})
+		templates.Network.ResourceCountIs(jsii.String("AWS::ElasticLoadBalancingV2::TargetGroup"), jsii.Number(1))
+		templates.App.ResourceCountIs(jsii.String("AWS::CodeDeploy::DeploymentGroup"), jsii.Number(0))
+	})
+
+	t.Run("failed rolling deployments roll back", func(t *testing.T) {
+		templates.App.HasResourceProperties(jsii.String("AWS::ECS::Service"), map[string]interface{}{
+			"HealthCheckGracePeriodSeconds": 120,
+			"DeploymentConfiguration": map[string]interface{}{
+				"MinimumHealthyPercent": 100,
+				"MaximumPercent":        200,
+				"DeploymentCircuitBreaker": map[string]interface{}{
+					"Enable":   true,
+					"Rollback": true,
+				},
+				"Alarms": map[string]interface{}{
+					"AlarmNames": []interface{}{
+						map[string]interface{}{"Ref": assertions.Match_StringLikeRegexp(jsii.String("Target5xxRateAlarm"))},
+						map[string]interface{}{"Ref": assertions.Match_StringLikeRegexp(jsii.String("TargetResponseTimeAlarm"))},
+						map[string]interface{}{"Ref": assertions.Match_StringLikeRegexp(jsii.String("UnhealthyHostsAlarm"))},
+					},
+					"Enable":   true,
+					"Rollback": true,
+				},
+			},
+		})
+		templates.App.HasResourceProperties(jsii.String("AWS::CloudWatch::Alarm"), map[string]interface{}{
+			"AlarmName":          "rails-api-dev-target-5xx-rate",
+			"Threshold":          5,
+			"ComparisonOperator": "GreaterThanThreshold",
+		})
+		templates.App.HasResourceProperties(jsii.String("AWS::CloudWatch::Alarm"), map[string]interface{}{
+			"AlarmName":          "rails-api-dev-target-response-time",
+			"MetricName":         "TargetResponseTime",
+			"ExtendedStatistic":  "p95",
+			"Threshold":          2,
+			"ComparisonOperator": "GreaterThanThreshold",
+		})
+		templates.App.HasResourceProperties(jsii.String("AWS::CloudWatch::Alarm"), map[string]interface{}{
+			"AlarmName":          "rails-api-dev-unhealthy-hosts",
+			"MetricName":         "UnHealthyHostCount",
+			"Threshold":          1,
+			"ComparisonOperator": "GreaterThanOrEqualToThreshold",
+		})
+	})
+
+	t.Run("database credentials are not in plain text", func(t *testing.T) {
+		templates.App.HasResourceProperties(jsii.String("AWS::ECS::TaskDefinition"), map[string]interface{}{
+			"ContainerDefinitions": []interface{}{
+				assertions.Match_ObjectLike(&map[string]interface{}{
+					"Environment": assertions.Match_Not(assertions.Match_ArrayWith(&[]interface{}{
+						assertions.Match_ObjectLike(&map[string]interface{}{"Name": "DB_PASSWORD"}),
+					})),
+					"Secrets": assertions.Match_ArrayWith(&[]interface{}{
+						assertions.Match_ObjectLike(&map[string]interface{}{"Name": "DB_PASSWORD"}),
+					}),
+				}),
+			},
+		})
+		templates.Data.HasResourceProperties(jsii.String("AWS::SecretsManager::Secret"), map[string]interface{}{
+			"Name": "rails-api-dev-db-credentials",
+		})
+	})
+
+	t.Run("execution role has no managed policies", func(t *testing.T) {
+		// ログの出力とシークレットの読み取りは、使うロググループとシークレットに限って許可する
+		templates.App.HasResourceProperties(jsii.String("AWS::IAM::Role"), map[string]interface{}{
+			"RoleName":          "rails-api-dev-execution-role",
+			"ManagedPolicyArns": assertions.Match_Absent(),
+		})
+		templates.App.HasResourceProperties(jsii.String("AWS::IAM::Policy"), map[string]interface{}{
+			"PolicyDocument": map[string]interface{}{
+				"Statement": assertions.Match_ArrayWith(&[]interface{}{
+					assertions.Match_ObjectLike(&map[string]interface{}{
+						"Action":   []interface{}{"logs:CreateLogStream", "logs:PutLogEvents"},
+						"Resource": map[string]interface{}{"Fn::GetAtt": []interface{}{assertions.Match_StringLikeRegexp(jsii.String("^ServiceLogGroup")), "Arn"}},
+					}),
+				}),
+			},
+		})
+	})
+
+	t.Run("rds engine and encryption", func(t *testing.T) {
+		templates.Data.HasResourceProperties(jsii.String("AWS::RDS::DBInstance"), map[string]interface{}{
+			"Engine":           "postgres",
+			"EngineVersion":    "16.4",
+			"DBInstanceClass":  "db.t3.micro",
+			"StorageEncrypted": true,
+			"MultiAZ":          false,
+		})
+	})
+}
+
+func TestRailsApiStackAutoScaling(t *testing.T) {
+	// GIVEN
+	cfg := testConfig()
+
+	// WHEN
+	_, templates := synthRailsApiStacks(t, cfg)
+
+	// THEN
+	t.Run("capacity range and schedules", func(t *testing.T) {
+		templates.App.HasResourceProperties(jsii.String("AWS::ApplicationAutoScaling::ScalableTarget"), map[string]interface{}{
+			"MinCapacity":       1,
+			"MaxCapacity":       2,
+			"ScalableDimension": "ecs:service:DesiredCount",
+			"ScheduledActions": []interface{}{
+				map[string]interface{}{
+					"ScheduledActionName":  "ScaleToZero",
+					"Schedule":             "cron(0 22 * * ? *)",
+					"Timezone":             "Asia/Tokyo",
+					"ScalableTargetAction": map[string]interface{}{"MinCapacity": 0, "MaxCapacity": 0},
+				},
+				map[string]interface{}{
+					"ScheduledActionName":  "ScaleUp",
+					"Schedule":             "cron(0 8 ? * MON-FRI *)",
+					"Timezone":             "Asia/Tokyo",
+					"ScalableTargetAction": map[string]interface{}{"MinCapacity": 1, "MaxCapacity": 2},
+				},
+			},
+		})
+	})
+
+	t.Run("target tracking on cpu, memory and requests per target", func(t *testing.T) {
+		templates.App.ResourceCountIs(jsii.String("AWS::ApplicationAutoScaling::ScalingPolicy"), jsii.Number(3))
+		for metric, target := range map[string]int{
+			"ECSServiceAverageCPUUtilization":    60,
+			"ECSServiceAverageMemoryUtilization": 75,
+			"ALBRequestCountPerTarget":           1000,
+		} {
+			templates.App.HasResourceProperties(jsii.String("AWS::ApplicationAutoScaling::ScalingPolicy"), map[string]interface{}{
+				"TargetTrackingScalingPolicyConfiguration": map[string]interface{}{
+					"PredefinedMetricSpecification": assertions.Match_ObjectLike(&map[string]interface{}{
+						"PredefinedMetricType": metric,
+					}),
+					"TargetValue":      target,
+					"ScaleInCooldown":  300,
+					"ScaleOutCooldown": 60,
+				},
+			})
+		}
+	})
+}
+
+func TestRailsApiStacksSplit(t *testing.T) {
+	// GIVEN
+	cfg := testConfig()
+	// ローテーション用 Lambda から RDS への SG ルールでスタック間の参照が循環しないこと
+	cfg.DBRotationDays = 30
+
+	// WHEN
+	stacks, templates := synthRailsApiStacks(t, cfg)
+
+	// THEN
+	t.Run("data stack has termination protection", func(t *testing.T) {
+		if !*stacks.Data.TerminationProtection() {
+			t.Error("data stack must have termination protection")
+		}
+		if *stacks.App.TerminationProtection() {
+			t.Error("app stack must be replaceable without disabling termination protection")
+		}
+	})
+
+	t.Run("stacks depend on network then data", func(t *testing.T) {
+		assertDependsOn(t, stacks.Data.Stack, stacks.Network.Stack)
+		assertDependsOn(t, stacks.App.Stack, stacks.Network.Stack, stacks.Data.Stack)
+	})
+
+	t.Run("resources are placed in their own stack", func(t *testing.T) {
+		templates.Network.ResourceCountIs(jsii.String("AWS::EC2::VPC"), jsii.Number(1))
+		templates.Network.ResourceCountIs(jsii.String("AWS::RDS::DBInstance"), jsii.Number(0))
+		templates.Data.ResourceCountIs(jsii.String("AWS::RDS::DBInstance"), jsii.Number(1))
+		templates.Data.ResourceCountIs(jsii.String("AWS::ECS::Service"), jsii.Number(0))
+		templates.App.ResourceCountIs(jsii.String("AWS::ECS::Service"), jsii.Number(1))
+		templates.App.ResourceCountIs(jsii.String("AWS::RDS::DBInstance"), jsii.Number(0))
+		templates.App.ResourceCountIs(jsii.String("AWS::EC2::VPC"), jsii.Number(0))
+	})
+
+	t.Run("app stack imports the database endpoint", func(t *testing.T) {
+		templates.App.HasResourceProperties(jsii.String("AWS::ECS::TaskDefinition"), map[string]interface{}{
+			"ContainerDefinitions": []interface{}{
+				assertions.Match_ObjectLike(&map[string]interface{}{
+					"Environment": assertions.Match_ArrayWith(&[]interface{}{
+						map[string]interface{}{
+							"Name":  "DB_HOST",
+							"Value": map[string]interface{}{"Fn::ImportValue": assertions.Match_AnyValue()},
+						},
+					}),
+				}),
+			},
+		})
+	})
+}
+
+func assertDependsOn(t *testing.T, stack awscdk.Stack, want ...awscdk.Stack) {
+	t.Helper()
+
+	deps := map[string]bool{}
+	for _, dep := range *stack.Dependencies() {
+		deps[*dep.StackName()] = true
+	}
+	for _, w := range want {
+		if !deps[*w.StackName()] {
+			t.Errorf("%s does not depend on %s", *stack.StackName(), *w.StackName())
+		}
+	}
+}
+
+func TestRailsApiStackVpcEndpoints(t *testing.T) {
+	// GIVEN
+	cfg := testConfig()
+	cfg.Region = "us-east-1"
+	cfg.InterfaceEndpoints = append(cfg.InterfaceEndpoints, "ssm", "ssmmessages", "sts")
+
+	// WHEN
+	_, templates := synthRailsApiStacks(t, cfg)
+
+	// THEN
+	templates.Network.ResourceCountIs(jsii.String("AWS::EC2::VPCEndpoint"), jsii.Number(8))
+	for _, name := range []string{"ecr.api", "ecr.dkr", "logs", "secretsmanager", "ssm", "ssmmessages", "sts"} {
+		templates.Network.HasResourceProperties(jsii.String("AWS::EC2::VPCEndpoint"), map[string]interface{}{
+			"ServiceName":     "com.amazonaws.us-east-1." + name,
+			"VpcEndpointType": "Interface",
+		})
+	}
+
+	resources := templates.Network.FindResources(jsii.String("AWS::EC2::VPCEndpoint"), nil)
+	for logicalID := range *resources {
+		if strings.Contains(logicalID, "apnortheast1") {
+			t.Errorf("endpoint %s is named after ap-northeast-1 in a us-east-1 stack", logicalID)
+		}
+	}
+}
+
+func TestRailsApiStackBlueGreen(t *testing.T) {
+	// GIVEN
+	cfg := testConfig()
+	cfg.DeploymentMode = config.DeploymentModeBlueGreen
+	cfg.OfficeCidrs = []string{"203.0.113.0/24"}
+
+	// WHEN
+	_, templates := synthRailsApiStacks(t, cfg)
+
+	// THEN
+	templates.Network.HasResourceProperties(jsii.String("AWS::ElasticLoadBalancingV2::TargetGroup"), map[string]interface{}{
+		"Name":            "rails-api-dev-tg2",
+		"Port":            3000,
+		"HealthCheckPath": "/up",
+	})
+	templates.Network.HasResourceProperties(jsii.String("AWS::ElasticLoadBalancingV2::Listener"), map[string]interface{}{
+		"Port":     8443,
+		"Protocol": "HTTPS",
+	})
+	// テストリスナーはオフィスの CIDR からのみ到達できる
+	templates.Network.HasResourceProperties(jsii.String("AWS::EC2::SecurityGroup"), map[string]interface{}{
+		"GroupName": "rails-api-dev-sg-alb",
+		"SecurityGroupIngress": []interface{}{
+			assertions.Match_ObjectLike(&map[string]interface{}{"CidrIp": "0.0.0.0/0", "FromPort": 80}),
+			assertions.Match_ObjectLike(&map[string]interface{}{"CidrIp": "0.0.0.0/0", "FromPort": 443}),
+			assertions.Match_ObjectLike(&map[string]interface{}{"CidrIp": "203.0.113.0/24", "FromPort": 8443, "ToPort": 8443}),
+		},
+	})
+	templates.App.HasResourceProperties(jsii.String("AWS::ECS::Service"), map[string]interface{}{
+		"DeploymentController": map[string]interface{}{
+			"Type": "CODE_DEPLOY",
+		},
+		// ロールバックは CodeDeploy が行う
+		"DeploymentConfiguration": map[string]interface{}{
+			"MaximumPercent":        200,
+			"MinimumHealthyPercent": 50,
+		},
+	})
+	// blue / green どちらのターゲットグループも監視し、アラームでロールバックする
+	templates.App.ResourcePropertiesCountIs(jsii.String("AWS::CloudWatch::Alarm"), map[string]interface{}{
+		"AlarmName": assertions.Match_StringLikeRegexp(jsii.String("^rails-api-dev-tg[12]-")),
+	}, jsii.Number(6))
+	for _, name := range []string{"rails-api-dev-tg1-target-5xx-rate", "rails-api-dev-tg2-target-5xx-rate"} {
+		templates.App.HasResourceProperties(jsii.String("AWS::CloudWatch::Alarm"), map[string]interface{}{
+			"AlarmName": name,
+		})
+	}
+	templates.App.HasResourceProperties(jsii.String("AWS::CodeDeploy::DeploymentGroup"), map[string]interface{}{
+		"DeploymentGroupName":  "rails-api-dev-deployment-group",
+		"DeploymentConfigName": "CodeDeployDefault.ECSAllAtOnce",
+		"DeploymentStyle": map[string]interface{}{
+			"DeploymentOption": "WITH_TRAFFIC_CONTROL",
+			"DeploymentType":   "BLUE_GREEN",
+		},
+		"AlarmConfiguration": map[string]interface{}{
+			"Alarms":  assertions.Match_AnyValue(),
+			"Enabled": true,
+		},
+		"AutoRollbackConfiguration": map[string]interface{}{
+			"Enabled": true,
+			"Events":  []interface{}{"DEPLOYMENT_FAILURE", "DEPLOYMENT_STOP_ON_REQUEST", "DEPLOYMENT_STOP_ON_ALARM"},
+		},
+		"BlueGreenDeploymentConfiguration": map[string]interface{}{
+			"TerminateBlueInstancesOnDeploymentSuccess": map[string]interface{}{
+				"Action":                       "TERMINATE",
+				"TerminationWaitTimeInMinutes": 0,
+			},
+			"DeploymentReadyOption": assertions.Match_AnyValue(),
+		},
+	})
+	// AWSCodeDeployRoleForECS の代わりに、サービスとリスナー、アラームに限った権限を持つ
+	templates.App.HasResourceProperties(jsii.String("AWS::IAM::Role"), map[string]interface{}{
+		"RoleName":          "rails-api-dev-deploy-role",
+		"ManagedPolicyArns": assertions.Match_Absent(),
+	})
+	templates.App.HasResourceProperties(jsii.String("AWS::IAM::Policy"), map[string]interface{}{
+		"PolicyDocument": map[string]interface{}{
+			"Statement": assertions.Match_ArrayWith(&[]interface{}{
+				assertions.Match_ObjectLike(&map[string]interface{}{
+					"Action":   "elasticloadbalancing:ModifyListener",
+					"Resource": []interface{}{assertions.Match_AnyValue(), assertions.Match_AnyValue()},
+				}),
+				assertions.Match_ObjectLike(&map[string]interface{}{
+					"Action":   "cloudwatch:DescribeAlarms",
+					"Resource": map[string]interface{}{"Fn::GetAtt": []interface{}{assertions.Match_StringLikeRegexp(jsii.String("Target5xxRateAlarm")), "Arn"}},
+				}),
+			}),
+		},
+	})
+}
+
+func TestRailsApiStackTrafficShifting(t *testing.T) {
+	tests := []struct {
+		name       string
+		shifting   config.BlueGreenDeployment
+		configName interface{}
+	}{
+		{
+			name:       "predefined canary",
+			shifting:   config.BlueGreenDeployment{TrafficShifting: deployment.TrafficShiftingCanary, TrafficShiftingPercent: 10, TrafficShiftingIntervalMinutes: 5},
+			configName: "CodeDeployDefault.ECSCanary10Percent5Minutes",
+		},
+		{
+			name:       "custom linear",
+			shifting:   config.BlueGreenDeployment{TrafficShifting: deployment.TrafficShiftingLinear, TrafficShiftingPercent: 25, TrafficShiftingIntervalMinutes: 2},
+			configName: map[string]interface{}{"Ref": assertions.Match_StringLikeRegexp(jsii.String("DeploymentConfig"))},
+		},
+		{
+			name:       "existing config",
+			shifting:   config.BlueGreenDeployment{TrafficShifting: deployment.TrafficShiftingCustom, DeploymentConfigName: "my-config"},
+			configName: "my-config",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN
+			cfg := testConfig()
+			cfg.DeploymentMode = config.DeploymentModeBlueGreen
+			cfg.OfficeCidrs = []string{"203.0.113.0/24"}
+			cfg.BlueGreen = tt.shifting
+			cfg.BlueGreen.TerminationWaitMinutes = 60
+
+			// WHEN
+			_, templates := synthRailsApiStacks(t, cfg)
+
+			// THEN
+			templates.App.HasResourceProperties(jsii.String("AWS::CodeDeploy::DeploymentGroup"), map[string]interface{}{
+				"DeploymentConfigName": tt.configName,
+				"BlueGreenDeploymentConfiguration": assertions.Match_ObjectLike(&map[string]interface{}{
+					"TerminateBlueInstancesOnDeploymentSuccess": map[string]interface{}{
+						"Action":                       "TERMINATE",
+						"TerminationWaitTimeInMinutes": 60,
+					},
+				}),
+			})
+		})
+	}
+}
+
+func TestRailsApiStacksCompliance(t *testing.T) {
+	for _, mode := range []config.DeploymentMode{config.DeploymentModeRolling, config.DeploymentModeBlueGreen} {
+		for _, db := range []struct {
+			profile database.Profile
+			engine  database.Engine
+			proxy   bool
+		}{
+			{database.ProfileFreeTier, database.EnginePostgres, false},
+			{database.ProfileProduction, database.EnginePostgres, false},
+			{database.ProfileProduction, database.EngineAuroraPostgres, false},
+			{database.ProfileProduction, database.EngineAuroraPostgres, true},
+		} {
+			profile := db.profile
+			name := string(mode) + "/" + string(profile) + "/" + string(db.engine)
+			if db.proxy {
+				name += "/proxy"
+			}
+			t.Run(name, func(t *testing.T) {
+				// GIVEN
+				cfg := testConfig()
+				cfg.DeploymentMode = mode
+				cfg.DBRotationDays = 30
+				cfg.DBProfile.Profile = profile
+				cfg.Sizing.DBMultiAz = profile == database.ProfileProduction
+				cfg.DBEngine = config.DatabaseEngine{
+					Engine:                db.engine,
+					ServerlessMinCapacity: 0.5,
+					ServerlessMaxCapacity: 4,
+				}
+				cfg.DBMonitoring.Level = database.MonitoringLevelFull
+				cfg.DBProxy.Enabled = db.proxy
+				cfg.OfficeCidrs = []string{"203.0.113.0/24"}
+				app := awscdk.NewApp(&awscdk.AppProps{
+					Context: testContext(cfg.Region),
+				})
+				awscdk.Aspects_Of(app).Add(security.NewComplianceChecks(), nil)
+
+				// WHEN
+				stacks := NewRailsApiStacks(app, "rails-api-test", &RailsApiStackProps{
+					StackProps: awscdk.StackProps{
+						Env: env(cfg),
+					},
+					Config: cfg,
+				})
+
+				// THEN
+				for _, stack := range []awscdk.Stack{stacks.Network.Stack, stacks.Data.Stack, stacks.App.Stack} {
+					assertions.Annotations_FromStack(stack).HasNoError(jsii.String("*"), assertions.Match_AnyValue())
+				}
+				// production は削除保護があり、抑制なしで StatefulRemovalPolicy を満たす
+				if profile == database.ProfileProduction {
+					assertions.Annotations_FromStack(stacks.Data.Stack).HasNoWarning(jsii.String("*"), assertions.Match_StringLikeRegexp(jsii.String("DeletionProtection")))
+				}
+			})
+		}
+	}
+}
+
+func TestRailsApiStackDatabaseProfile(t *testing.T) {
+	t.Run("free-tier deletes the database with the stack", func(t *testing.T) {
+		// GIVEN
+		cfg := testConfig()
+		cfg.DBMonitoring.Level = database.MonitoringLevelBasic
+
+		// WHEN
+		_, templates := synthRailsApiStacks(t, cfg)
+
+		// THEN
+		templates.Data.HasResource(jsii.String("AWS::RDS::DBInstance"), map[string]interface{}{
+			"DeletionPolicy": "Delete",
+			"Properties": assertions.Match_ObjectLike(&map[string]interface{}{
+				"DBInstanceIdentifier":       "rails-api-dev-db",
+				"MultiAZ":                    false,
+				"DeletionProtection":         false,
+				"DeleteAutomatedBackups":     true,
+				"PreferredBackupWindow":      assertions.Match_Absent(),
+				"PreferredMaintenanceWindow": assertions.Match_Absent(),
+			}),
+		})
+		templates.Data.HasResource(jsii.String("AWS::Logs::LogGroup"), map[string]interface{}{
+			"DeletionPolicy": "Delete",
+		})
+	})
+
+	t.Run("production keeps a snapshot and automated backups", func(t *testing.T) {
+		// GIVEN
+		cfg := testConfig()
+		cfg.DBProfile = config.DatabaseProfile{
+			Profile:           database.ProfileProduction,
+			BackupWindow:      "18:00-19:00",
+			MaintenanceWindow: "sun:19:00-sun:20:00",
+		}
+		cfg.Sizing.DBMultiAz = true
+		cfg.DBMonitoring.Level = database.MonitoringLevelBasic
+
+		// WHEN
+		_, templates := synthRailsApiStacks(t, cfg)
+
+		// THEN
+		templates.Data.HasResource(jsii.String("AWS::RDS::DBInstance"), map[string]interface{}{
+			"DeletionPolicy":      "Snapshot",
+			"UpdateReplacePolicy": "Snapshot",
+			"Properties": assertions.Match_ObjectLike(&map[string]interface{}{
+				"DBInstanceIdentifier":       "rails-api-dev-db",
+				"MultiAZ":                    true,
+				"DeletionProtection":         true,
+				"DeleteAutomatedBackups":     false,
+				"PreferredBackupWindow":      "18:00-19:00",
+				"PreferredMaintenanceWindow": "sun:19:00-sun:20:00",
+			}),
+		})
+		// RDS のログもスタックの削除後に調査できるように残す
+		templates.Data.HasResource(jsii.String("AWS::Logs::LogGroup"), map[string]interface{}{
+			"DeletionPolicy":      "Retain",
+			"UpdateReplacePolicy": "Retain",
+			"Properties": assertions.Match_ObjectLike(&map[string]interface{}{
+				"LogGroupName": "/aws/rds/instance/rails-api-dev-db/postgresql",
+			}),
+		})
+	})
+
+	t.Run("an existing identifier is kept", func(t *testing.T) {
+		// GIVEN
+		cfg := testConfig()
+		cfg.DBProfile.Identifier = "database-1"
+
+		// WHEN
+		_, templates := synthRailsApiStacks(t, cfg)
+
+		// THEN
+		templates.Data.HasResourceProperties(jsii.String("AWS::RDS::DBInstance"), map[string]interface{}{
+			"DBInstanceIdentifier": "database-1",
+		})
+	})
+}
+
+func TestRailsApiStackAurora(t *testing.T) {
+	t.Run("provisioned cluster has a writer and readers", func(t *testing.T) {
+		// GIVEN
+		cfg := testConfig()
+		cfg.Sizing.DBInstanceType = "t4g.medium"
+		cfg.DBEngine = config.DatabaseEngine{
+			Engine:        database.EngineAuroraPostgres,
+			AuroraReaders: 2,
+		}
+		cfg.DBMonitoring.Level = database.MonitoringLevelFull
+
+		// WHEN
+		_, templates := synthRailsApiStacks(t, cfg)
+
+		// THEN
+		templates.Data.ResourceCountIs(jsii.String("AWS::RDS::DBCluster"), jsii.Number(1))
+		templates.Data.HasResourceProperties(jsii.String("AWS::RDS::DBCluster"), map[string]interface{}{
+			"DBClusterIdentifier":         "rails-api-dev-db",
+			"Engine":                      "aurora-postgresql",
+			"EngineVersion":               "16.4",
+			"DatabaseName":                "rails_api_production",
+			"StorageEncrypted":            true,
+			"PerformanceInsightsEnabled":  true,
+			"EnableCloudwatchLogsExports": []interface{}{"postgresql"},
+		})
+		templates.Data.ResourceCountIs(jsii.String("AWS::RDS::DBInstance"), jsii.Number(3))
+		for _, id := range []string{"writer", "reader-1", "reader-2"} {
+			templates.Data.HasResourceProperties(jsii.String("AWS::RDS::DBInstance"), map[string]interface{}{
+				"DBInstanceIdentifier": "rails-api-dev-db-" + id,
+				"DBInstanceClass":      "db.t4g.medium",
+				"MonitoringInterval":   60,
+			})
+		}
+		templates.Data.HasResourceProperties(jsii.String("AWS::Logs::LogGroup"), map[string]interface{}{
+			"LogGroupName": "/aws/rds/cluster/rails-api-dev-db/postgresql",
+		})
+	})
+
+	t.Run("serverless v2 scales between the ACU limits", func(t *testing.T) {
+		// GIVEN
+		cfg := testConfig()
+		cfg.DBEngine = config.DatabaseEngine{
+			Engine:                database.EngineAuroraPostgres,
+			ServerlessMinCapacity: 0.5,
+			ServerlessMaxCapacity: 8,
+		}
+		cfg.DBProfile.Profile = database.ProfileProduction
+		cfg.Sizing.DBMultiAz = true
+
+		// WHEN
+		_, templates := synthRailsApiStacks(t, cfg)
+
+		// THEN
+		templates.Data.HasResource(jsii.String("AWS::RDS::DBCluster"), map[string]interface{}{
+			"DeletionPolicy": "Snapshot",
+			"Properties": assertions.Match_ObjectLike(&map[string]interface{}{
+				"DeletionProtection": true,
+				"ServerlessV2ScalingConfiguration": map[string]interface{}{
+					"MinCapacity": 0.5,
+					"MaxCapacity": 8,
+				},
+			}),
+		})
+		// マルチ AZ にするため、リーダーを 1 台置く
+		templates.Data.ResourceCountIs(jsii.String("AWS::RDS::DBInstance"), jsii.Number(2))
+		templates.Data.HasResourceProperties(jsii.String("AWS::RDS::DBInstance"), map[string]interface{}{
+			"DBInstanceIdentifier": "rails-api-dev-db-reader-1",
+			"DBInstanceClass":      "db.serverless",
+			"PromotionTier":        1,
+		})
+	})
+
+	t.Run("rails connects to the writer and reader endpoints", func(t *testing.T) {
+		// GIVEN
+		cfg := testConfig()
+		cfg.DBEngine = config.DatabaseEngine{
+			Engine:                database.EngineAuroraPostgres,
+			AuroraReaders:         1,
+			ServerlessMinCapacity: 0.5,
+			ServerlessMaxCapacity: 4,
+		}
+
+		// WHEN
+		_, templates := synthRailsApiStacks(t, cfg)
+
+		// THEN
+		importOf := func(pattern string) map[string]interface{} {
+			return map[string]interface{}{
+				"Fn::ImportValue": assertions.Match_StringLikeRegexp(jsii.String(pattern)),
+			}
+		}
+		templates.App.HasResourceProperties(jsii.String("AWS::ECS::TaskDefinition"), map[string]interface{}{
+			"ContainerDefinitions": []interface{}{
+				assertions.Match_ObjectLike(&map[string]interface{}{
+					"Environment": assertions.Match_ArrayWith(&[]interface{}{
+						map[string]interface{}{"Name": "DB_HOST", "Value": importOf("GetAttDatabaseCluster[0-9A-F]{8}EndpointAddress")},
+						map[string]interface{}{"Name": "DB_PORT", "Value": importOf("GetAttDatabaseCluster[0-9A-F]{8}EndpointPort")},
+						map[string]interface{}{"Name": "DB_READER_HOST", "Value": importOf("GetAttDatabaseCluster[0-9A-F]{8}ReadEndpointAddress")},
+					}),
+					"Secrets": assertions.Match_ArrayWith(&[]interface{}{
+						assertions.Match_ObjectLike(&map[string]interface{}{"Name": "DB_PASSWORD"}),
+					}),
+				}),
+			},
+		})
+		// メトリクスはクラスター単位
+		templates.App.HasResourceProperties(jsii.String("AWS::CloudWatch::Alarm"), map[string]interface{}{
+			"AlarmName":  "rails-api-dev-rds-free-storage",
+			"MetricName": "FreeLocalStorage",
+			"Dimensions": []interface{}{
+				map[string]interface{}{"Name": "DBClusterIdentifier", "Value": importOf("RefDatabaseCluster")},
+			},
+		})
+	})
+}
+
+func TestRailsApiStackDatabaseProxy(t *testing.T) {
+	importOf := func(pattern string) map[string]interface{} {
+		return map[string]interface{}{
+			"Fn::ImportValue": assertions.Match_StringLikeRegexp(jsii.String(pattern)),
+		}
+	}
+
+	t.Run("rails connects through the proxy with IAM auth and TLS", func(t *testing.T) {
+		// GIVEN
+		cfg := testConfig()
+		cfg.DBProxy = config.DatabaseProxy{
+			Enabled:               true,
+			MaxConnectionsPercent: 80,
+		}
+
+		// WHEN
+		_, templates := synthRailsApiStacks(t, cfg)
+
+		// THEN
+		templates.Data.HasResourceProperties(jsii.String("AWS::RDS::DBProxy"), map[string]interface{}{
+			"DBProxyName":  "rails-api-dev-db-proxy",
+			"EngineFamily": "POSTGRESQL",
+			"RequireTLS":   true,
+			"Auth": []interface{}{
+				assertions.Match_ObjectLike(&map[string]interface{}{
+					"AuthScheme": "SECRETS",
+					"IAMAuth":    "REQUIRED",
+				}),
+			},
+		})
+		templates.Data.HasResourceProperties(jsii.String("AWS::RDS::DBProxyTargetGroup"), map[string]interface{}{
+			"ConnectionPoolConfigurationInfo": map[string]interface{}{
+				"MaxConnectionsPercent": 80,
+			},
+		})
+		// プロキシは isolated サブネットに置く
+		templates.Data.HasResourceProperties(jsii.String("AWS::RDS::DBProxy"), map[string]interface{}{
+			"VpcSubnetIds": assertions.Match_ArrayWith(&[]interface{}{
+				importOf("RefNetworkVpcisolatedSubnet"),
+			}),
+		})
+
+		// ECS → プロキシ → DB の順にだけ通す
+		getAtt := func(pattern string) map[string]interface{} {
+			return map[string]interface{}{
+				"Fn::GetAtt": assertions.Match_ArrayWith(&[]interface{}{assertions.Match_StringLikeRegexp(jsii.String(pattern))}),
+			}
+		}
+		templates.Data.ResourceCountIs(jsii.String("AWS::EC2::SecurityGroupIngress"), jsii.Number(2))
+		templates.Data.HasResourceProperties(jsii.String("AWS::EC2::SecurityGroupIngress"), map[string]interface{}{
+			"FromPort":              5432,
+			"GroupId":               getAtt("DatabaseProxySecurityGroup"),
+			"SourceSecurityGroupId": importOf("EcsSecurityGroup"),
+		})
+		templates.Data.HasResourceProperties(jsii.String("AWS::EC2::SecurityGroupIngress"), map[string]interface{}{
+			"GroupId":               getAtt("DatabaseSecurityGroup"),
+			"SourceSecurityGroupId": getAtt("DatabaseProxySecurityGroup"),
+		})
+		templates.Data.HasResourceProperties(jsii.String("AWS::EC2::SecurityGroupEgress"), map[string]interface{}{
+			"GroupId":                    getAtt("DatabaseProxySecurityGroup"),
+			"DestinationSecurityGroupId": getAtt("DatabaseSecurityGroup"),
+		})
+		// 認証情報は Secrets Manager の VPC エンドポイントから取得する
+		templates.Data.HasResourceProperties(jsii.String("AWS::EC2::SecurityGroup"), map[string]interface{}{
+			"GroupName": "rails-api-dev-sg-rds-proxy",
+			"SecurityGroupEgress": assertions.Match_ArrayWith(&[]interface{}{
+				assertions.Match_ObjectLike(&map[string]interface{}{
+					"CidrIp":     importOf("NetworkVpc[0-9A-F]{8}CidrBlock"),
+					"FromPort":   443,
+					"ToPort":     443,
+					"IpProtocol": "tcp",
+				}),
+			}),
+		})
+
+		templates.App.HasResourceProperties(jsii.String("AWS::ECS::TaskDefinition"), map[string]interface{}{
+			"ContainerDefinitions": []interface{}{
+				assertions.Match_ObjectLike(&map[string]interface{}{
+					"Environment": assertions.Match_ArrayWith(&[]interface{}{
+						map[string]interface{}{"Name": "DB_HOST", "Value": importOf("GetAttDatabaseProxy[0-9A-F]{8}Endpoint")},
+						map[string]interface{}{"Name": "DB_IAM_AUTH", "Value": "true"},
+						map[string]interface{}{"Name": "DB_READER_HOST", "Value": importOf("GetAttDatabaseProxy[0-9A-F]{8}Endpoint")},
+						map[string]interface{}{"Name": "DB_SSLMODE", "Value": "require"},
+					}),
+				}),
+			},
+		})
+		// タスクロールにプロキシへの接続を許可する
+		templates.App.HasResourceProperties(jsii.String("AWS::IAM::Policy"), map[string]interface{}{
+			"PolicyDocument": map[string]interface{}{
+				"Statement": assertions.Match_ArrayWith(&[]interface{}{
+					assertions.Match_ObjectLike(&map[string]interface{}{
+						"Action": "rds-db:connect",
+						"Effect": "Allow",
+					}),
+				}),
+			},
+		})
+	})
+
+	t.Run("aurora readers are reached through a read-only proxy endpoint", func(t *testing.T) {
+		// GIVEN
+		cfg := testConfig()
+		cfg.DBEngine = config.DatabaseEngine{
+			Engine:                database.EngineAuroraPostgres,
+			AuroraReaders:         1,
+			ServerlessMinCapacity: 0.5,
+			ServerlessMaxCapacity: 4,
+		}
+		cfg.DBProxy.Enabled = true
+
+		// WHEN
+		_, templates := synthRailsApiStacks(t, cfg)
+
+		// THEN
+		templates.Data.HasResourceProperties(jsii.String("AWS::RDS::DBProxyEndpoint"), map[string]interface{}{
+			"DBProxyEndpointName": "rails-api-dev-db-proxy-reader",
+			"TargetRole":          "READ_ONLY",
+		})
+		templates.App.HasResourceProperties(jsii.String("AWS::ECS::TaskDefinition"), map[string]interface{}{
+			"ContainerDefinitions": []interface{}{
+				assertions.Match_ObjectLike(&map[string]interface{}{
+					"Environment": assertions.Match_ArrayWith(&[]interface{}{
+						map[string]interface{}{"Name": "DB_READER_HOST", "Value": importOf("GetAttDatabaseProxyReaderEndpoint")},
+					}),
+				}),
+			},
+		})
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		// WHEN
+		_, templates := synthRailsApiStacks(t, testConfig())
+
+		// THEN
+		templates.Data.ResourceCountIs(jsii.String("AWS::RDS::DBProxy"), jsii.Number(0))
+	})
+}
+
+func TestRailsApiStackDatabaseMonitoring(t *testing.T) {
+	t.Run("off disables performance insights, enhanced monitoring and log exports", func(t *testing.T) {
+		// GIVEN
+		cfg := testConfig()
+
+		// WHEN
+		_, templates := synthRailsApiStacks(t, cfg)
+
+		// THEN
+		templates.Data.HasResourceProperties(jsii.String("AWS::RDS::DBInstance"), map[string]interface{}{
+			"EnablePerformanceInsights":   false,
+			"MonitoringInterval":          0,
+			"EnableCloudwatchLogsExports": []interface{}{},
+		})
+		templates.Data.ResourceCountIs(jsii.String("AWS::Logs::LogGroup"), jsii.Number(0))
+		templates.Data.HasResourceProperties(jsii.String("AWS::RDS::DBParameterGroup"), map[string]interface{}{
+			"Parameters": map[string]interface{}{
+				"shared_preload_libraries": "pg_stat_statements",
+			},
+		})
+	})
+
+	t.Run("basic enables performance insights and postgresql logs", func(t *testing.T) {
+		// GIVEN
+		cfg := testConfig()
+		cfg.DBMonitoring.Level = database.MonitoringLevelBasic
+		cfg.DBMonitoring.SlowQueryMillis = 1000
+
+		// WHEN
+		_, templates := synthRailsApiStacks(t, cfg)
+
+		// THEN
+		templates.Data.HasResourceProperties(jsii.String("AWS::RDS::DBInstance"), map[string]interface{}{
+			"EnablePerformanceInsights":          true,
+			"PerformanceInsightsRetentionPeriod": 7,
+			"MonitoringInterval":                 0,
+			"EnableCloudwatchLogsExports":        []interface{}{"postgresql"},
+		})
+		templates.Data.HasResourceProperties(jsii.String("AWS::Logs::LogGroup"), map[string]interface{}{
+			"LogGroupName":    "/aws/rds/instance/rails-api-dev-db/postgresql",
+			"RetentionInDays": 7,
+		})
+		templates.Data.ResourceCountIs(jsii.String("AWS::IAM::Role"), jsii.Number(0))
+		templates.Data.HasResourceProperties(jsii.String("AWS::RDS::DBParameterGroup"), map[string]interface{}{
+			"Parameters": map[string]interface{}{
+				"shared_preload_libraries":   "pg_stat_statements",
+				"log_min_duration_statement": "1000",
+				"log_lock_waits":             "1",
+			},
+		})
+	})
+
+	t.Run("full adds enhanced monitoring and upgrade logs", func(t *testing.T) {
+		// GIVEN
+		cfg := testConfig()
+		cfg.DBMonitoring = config.DatabaseMonitoring{
+			Level:                     database.MonitoringLevelFull,
+			PerformanceInsightsMonths: 3,
+			IntervalSeconds:           30,
+		}
+
+		// WHEN
+		_, templates := synthRailsApiStacks(t, cfg)
+
+		// THEN
+		templates.Data.HasResourceProperties(jsii.String("AWS::RDS::DBInstance"), map[string]interface{}{
+			"EnablePerformanceInsights":          true,
+			"PerformanceInsightsRetentionPeriod": 93,
+			"MonitoringInterval":                 30,
+			"MonitoringRoleArn": map[string]interface{}{
+				"Fn::GetAtt": []interface{}{assertions.Match_StringLikeRegexp(jsii.String("^DatabaseMonitoringRole")), "Arn"},
+			},
+			"EnableCloudwatchLogsExports": []interface{}{"postgresql", "upgrade"},
+		})
+		templates.Data.ResourceCountIs(jsii.String("AWS::Logs::LogGroup"), jsii.Number(2))
+		templates.Data.HasResourceProperties(jsii.String("AWS::IAM::Role"), map[string]interface{}{
+			"RoleName":          "rails-api-dev-rds-monitoring-role",
+			"ManagedPolicyArns": assertions.Match_Absent(),
+			"AssumeRolePolicyDocument": map[string]interface{}{
+				"Statement": []interface{}{
+					assertions.Match_ObjectLike(&map[string]interface{}{
+						"Principal": map[string]interface{}{"Service": "monitoring.rds.amazonaws.com"},
+					}),
+				},
+			},
+		})
+		// 遅いクエリのログは 0 の場合は出力しない
+		templates.Data.HasResourceProperties(jsii.String("AWS::RDS::DBParameterGroup"), map[string]interface{}{
+			"Parameters": map[string]interface{}{
+				"shared_preload_libraries": "pg_stat_statements",
+			},
+		})
+	})
+}
+
+func TestRailsApiStackObservability(t *testing.T) {
+	// GIVEN
+	cfg := testConfig()
+	cfg.Alarms.Emails = []string{"ops@example.com"}
+	cfg.Alarms.MaxDBConnections = 0
+
+	// WHEN
+	_, templates := synthRailsApiStacks(t, cfg)
+
+	// THEN
+	t.Run("alarms notify the sns topic", func(t *testing.T) {
+		templates.App.HasResourceProperties(jsii.String("AWS::SNS::Topic"), map[string]interface{}{
+			"TopicName": "rails-api-dev-alarms",
+		})
+		templates.App.HasResourceProperties(jsii.String("AWS::SNS::Subscription"), map[string]interface{}{
+			"Protocol": "email",
+			"Endpoint": "ops@example.com",
+		})
+		for _, name := range []string{
+			"rails-api-dev-alb-5xx-rate",
+			"rails-api-dev-response-time",
+			"rails-api-dev-ecs-cpu",
+			"rails-api-dev-ecs-memory",
+			"rails-api-dev-rds-cpu",
+			"rails-api-dev-rds-free-storage",
+			"rails-api-dev-rds-read-latency",
+			"rails-api-dev-rds-write-latency",
+		} {
+			templates.App.HasResourceProperties(jsii.String("AWS::CloudWatch::Alarm"), map[string]interface{}{
+				"AlarmName":    name,
+				"AlarmActions": []interface{}{map[string]interface{}{"Ref": assertions.Match_StringLikeRegexp(jsii.String("^ObservabilityAlarmTopic"))}},
+				"OKActions":    []interface{}{map[string]interface{}{"Ref": assertions.Match_StringLikeRegexp(jsii.String("^ObservabilityAlarmTopic"))}},
+			})
+		}
+		// しきい値が 0 の項目はアラームを作らない
+		templates.App.ResourcePropertiesCountIs(jsii.String("AWS::CloudWatch::Alarm"), map[string]interface{}{
+			"AlarmName": "rails-api-dev-rds-connections",
+		}, jsii.Number(0))
+	})
+
+	t.Run("thresholds are converted to metric units", func(t *testing.T) {
+		templates.App.HasResourceProperties(jsii.String("AWS::CloudWatch::Alarm"), map[string]interface{}{
+			"AlarmName":          "rails-api-dev-response-time",
+			"Threshold":          3,
+			"ComparisonOperator": "GreaterThanThreshold",
+		})
+		templates.App.HasResourceProperties(jsii.String("AWS::CloudWatch::Alarm"), map[string]interface{}{
+			"AlarmName":          "rails-api-dev-rds-free-storage",
+			"MetricName":         "FreeStorageSpace",
+			"Threshold":          2048 * 1024 * 1024,
+			"ComparisonOperator": "LessThanThreshold",
+		})
+	})
+
+	t.Run("5xx rate treats missing 5xx metrics as zero", func(t *testing.T) {
+		templates.App.HasResourceProperties(jsii.String("AWS::CloudWatch::Alarm"), map[string]interface{}{
+			"AlarmName": "rails-api-dev-alb-5xx-rate",
+			"Metrics": assertions.Match_ArrayWith(&[]interface{}{
+				assertions.Match_ObjectLike(&map[string]interface{}{
+					"Expression": "IF(requests > 0, 100 * (FILL(elb5xx, 0) + FILL(target5xx, 0)) / requests, 0)",
+				}),
+			}),
+		})
+	})
+
+	t.Run("dashboard covers alb, ecs and rds", func(t *testing.T) {
+		templates.App.HasResourceProperties(jsii.String("AWS::CloudWatch::Dashboard"), map[string]interface{}{
+			"DashboardName": "rails-api-dev-dashboard",
+			"DashboardBody": map[string]interface{}{
+				"Fn::Join": []interface{}{"", assertions.Match_ArrayWith(&[]interface{}{
+					assertions.Match_StringLikeRegexp(jsii.String(`RunningTaskCount`)),
+				})},
+			},
+		})
+		// 実行中のタスク数は Container Insights のメトリクス
+		templates.App.HasResourceProperties(jsii.String("AWS::ECS::Cluster"), map[string]interface{}{
+			"ClusterSettings": []interface{}{
+				map[string]interface{}{"Name": "containerInsights", "Value": "enabled"},
+			},
+		})
+	})
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"testing"
+
+	"iaclib/snapshottest"
+)
+
+// スナップショットを更新する場合は `go test . -run Snapshot -update` を実行する
+func TestRailsApiStackSnapshot(t *testing.T) {
+	_, templates := synthRailsApiStacks(t, testConfig())
+
+	snapshottest.Assert(t, "rails-api-network.template.json", templates.Network.ToJSON())
+	snapshottest.Assert(t, "rails-api-data.template.json", templates.Data.ToJSON())
+	snapshottest.Assert(t, "rails-api-app.template.json", templates.App.ToJSON())
+}